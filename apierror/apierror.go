@@ -0,0 +1,142 @@
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/this-is-a-bot/bot/logging"
+)
+
+// Stable error codes, clients may switch on them.
+const (
+	CodeInvalid          = "invalid_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeRateLimited      = "rate_limited"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeInternal         = "internal_error"
+)
+
+// Error is an error safe to show to clients.
+type Error struct {
+	// HTTP status to answer with.
+	Status int `json:"-"`
+
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// Problems of single fields, keyed by field name.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Create an error with the given status and code.
+func New(status int, code string, format string, args ...interface{}) *Error {
+	return &Error{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// The request is malformed.
+func Invalid(format string, args ...interface{}) *Error {
+	return New(http.StatusBadRequest, CodeInvalid, format, args...)
+}
+
+// A field of the request is invalid, e.g. InvalidField("cc", "must be a
+// two-letter country code").
+func InvalidField(field string, problem string) *Error {
+	err := Invalid("'%s' %s", field, problem)
+	err.Details = map[string]string{field: problem}
+	return err
+}
+
+// The resource doesn't exist, or isn't visible to the caller.
+func NotFound(format string, args ...interface{}) *Error {
+	return New(http.StatusNotFound, CodeNotFound, format, args...)
+}
+
+// The request conflicts with the current state of the resource.
+func Conflict(format string, args ...interface{}) *Error {
+	return New(http.StatusConflict, CodeConflict, format, args...)
+}
+
+// The caller isn't authenticated.
+func Unauthorized(format string, args ...interface{}) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, format, args...)
+}
+
+// The caller is authenticated but not allowed to do this.
+func Forbidden(format string, args ...interface{}) *Error {
+	return New(http.StatusForbidden, CodeForbidden, format, args...)
+}
+
+// The caller's app reached one of its quotas.
+func QuotaExceeded(format string, args ...interface{}) *Error {
+	return New(http.StatusForbidden, CodeQuotaExceeded, format, args...)
+}
+
+// Convert err to an Error. Unique violations become conflicts, anything
+// else unexpected is an internal error whose message is hidden.
+func From(err error) *Error {
+	switch e := err.(type) {
+	case *Error:
+		return e
+	case *pq.Error:
+		if e.Code.Name() == "unique_violation" {
+			return Conflict("resource already exists")
+		}
+	}
+	return New(http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// Write err to the client, as a JSON envelope if the client accepts JSON,
+// plain text otherwise. Internal errors are logged with the request ID.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	if e.Status >= http.StatusInternalServerError {
+		logging.Errorf(r.Context(), "%s %s: %v", r.Method, r.URL.Path, err)
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !acceptsJSON(r.Header.Get("Accept")) {
+		http.Error(w, e.Message, e.Status)
+		return
+	}
+
+	js, jsErr := json.Marshal(struct {
+		Error     *Error `json:"error"`
+		RequestID string `json:"request_id,omitempty"`
+	}{e, logging.RequestID(r.Context())})
+	if jsErr != nil {
+		http.Error(w, e.Message, e.Status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	w.Write(js)
+}
+
+// Handler answering with a fixed error, e.g. for unknown routes.
+func Handler(err *Error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, err)
+	}
+}
+
+// Whether an Accept header lists a JSON media type.
+func acceptsJSON(accept string) bool {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(mediaRange, ";", 2)[0])
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package apps
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const appTableName = "app"
+
+var (
+	// Returned when an app isn't registered.
+	ErrAppNotFound = errors.New("app not found")
+
+	// Returned when registering a name that's taken.
+	ErrAppExists = errors.New("app already exists")
+
+	namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+)
+
+var (
+	queryApps string
+	queryApp  string
+	insertApp string
+	updateApp string
+)
+
+var columns = []string{
+	"name", "timezone", "digest_frequency", "max_catalogs_per_user", "max_webhooks",
+	"created_at", "disabled_at",
+}
+
+// Prepare queries.
+func init() {
+	queryApps = fmt.Sprintf("SELECT %s FROM %s ORDER BY name", Columns(""), appTableName)
+	queryApp = fmt.Sprintf("SELECT %s FROM %s WHERE name = $1", Columns(""), appTableName)
+
+	insertApp = fmt.Sprintf(
+		"INSERT INTO %s (name, timezone, digest_frequency, max_catalogs_per_user, max_webhooks) "+
+			"VALUES ($1, $2, $3, $4, $5) RETURNING created_at",
+		appTableName)
+
+	// Keep the original time when disabling a disabled app.
+	updateApp = fmt.Sprintf(
+		"UPDATE %s SET timezone = $2, digest_frequency = $3, max_catalogs_per_user = $4, "+
+			"max_webhooks = $5, disabled_at = CASE WHEN $6 THEN coalesce(disabled_at, now()) END "+
+			"WHERE name = $1 RETURNING created_at, disabled_at",
+		appTableName)
+}
+
+// App is a tenant of the bot, e.g. one chat adapter. Every user, catalog,
+// key, digest and webhook belongs to one app.
+type App struct {
+	Name string `json:"name"`
+
+	// Timezone deciding when a tracking day starts for its users, the
+	// deployment's if empty.
+	Timezone string `json:"timezone,omitempty"`
+
+	// Frequency of digests subscribed without one.
+	DigestFrequency string `json:"digestFrequency"`
+
+	// Quotas, zero means unlimited.
+	MaxCatalogsPerUser int `json:"maxCatalogsPerUser"`
+	MaxWebhooks        int `json:"maxWebhooks"`
+
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Keys of disabled apps are rejected.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+}
+
+// Columns of an app row, qualified with the table alias if any, for
+// queries joining the app table. Scan them into Fields.
+func Columns(alias string) string {
+	if alias == "" {
+		return strings.Join(columns, ", ")
+	}
+	qualified := make([]string, len(columns))
+	for i, column := range columns {
+		qualified[i] = alias + "." + column
+	}
+	return strings.Join(qualified, ", ")
+}
+
+// Scan destinations of the app's Columns.
+func (a *App) Fields() []interface{} {
+	return []interface{}{
+		&a.Name, &a.Timezone, &a.DigestFrequency, &a.MaxCatalogsPerUser, &a.MaxWebhooks,
+		&a.CreatedAt, &a.DisabledAt,
+	}
+}
+
+// Check the name of a new app. Apps registered before names were checked
+// may have any name, so existing apps aren't held to it.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return errors.New("'name' must be lowercase letters, digits, '-' or '_'")
+	}
+	return nil
+}
+
+// Check the settings and fill in defaults.
+func (a *App) Validate() error {
+	if a.Timezone != "" {
+		if _, err := time.LoadLocation(a.Timezone); err != nil {
+			return fmt.Errorf("'timezone' is unknown: %v", err)
+		}
+	}
+	if a.DigestFrequency == "" {
+		a.DigestFrequency = "daily"
+	}
+	if a.DigestFrequency != "daily" && a.DigestFrequency != "weekly" {
+		return errors.New("'digestFrequency' must be daily or weekly")
+	}
+	if a.MaxCatalogsPerUser < 0 || a.MaxWebhooks < 0 {
+		return errors.New("quotas must not be negative")
+	}
+	return nil
+}
+
+// Whether the app may be used.
+func (a *App) Enabled() bool {
+	return a.DisabledAt == nil
+}
+
+// The app's timezone, nil if it uses the deployment's.
+func (a *App) Location() *time.Location {
+	if a.Timezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(a.Timezone)
+	if err != nil {
+		// Validated when saved, the zone database must have changed.
+		return nil
+	}
+	return loc
+}
+
+// Register a new app, ValidateName and Validate it first.
+func Create(ctx context.Context, db *sql.DB, a *App) error {
+	err := db.QueryRowContext(
+		ctx, insertApp, a.Name, a.Timezone, a.DigestFrequency, a.MaxCatalogsPerUser,
+		a.MaxWebhooks).Scan(&a.CreatedAt)
+	if e, ok := err.(*pq.Error); ok && e.Code.Name() == "unique_violation" {
+		return ErrAppExists
+	}
+	return err
+}
+
+// Save the settings of an app, Validate it first.
+func Update(ctx context.Context, db *sql.DB, a *App) error {
+	err := db.QueryRowContext(
+		ctx, updateApp, a.Name, a.Timezone, a.DigestFrequency, a.MaxCatalogsPerUser,
+		a.MaxWebhooks, a.DisabledAt != nil).Scan(&a.CreatedAt, &a.DisabledAt)
+	if err == sql.ErrNoRows {
+		return ErrAppNotFound
+	}
+	return err
+}
+
+// Get a registered app.
+func Get(ctx context.Context, db *sql.DB, name string) (*App, error) {
+	var a App
+	err := db.QueryRowContext(ctx, queryApp, name).Scan(a.Fields()...)
+	if err == sql.ErrNoRows {
+		return nil, ErrAppNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Get all apps, disabled ones included.
+func List(ctx context.Context, db *sql.DB) ([]App, error) {
+	rows, err := db.QueryContext(ctx, queryApps)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]App, 0)
+	for rows.Next() {
+		var a App
+		if err := rows.Scan(a.Fields()...); err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+type contextKey struct{}
+
+// Attach the app a request acts in to the context.
+func NewContext(ctx context.Context, a *App) context.Context {
+	return context.WithValue(ctx, contextKey{}, a)
+}
+
+// Get the app attached to the context, nil if none.
+func FromContext(ctx context.Context) *App {
+	a, _ := ctx.Value(contextKey{}).(*App)
+	return a
+}
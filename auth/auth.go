@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/this-is-a-bot/bot/apierror"
+	"github.com/this-is-a-bot/bot/apps"
+	"github.com/this-is-a-bot/bot/logging"
+)
+
+const apiKeyTableName = "api_key"
+const appTableName = "app"
+
+// Prefix of issued keys, makes them easy to spot in logs and configs.
+const keyPrefix = "bot_"
+
+var (
+	queryKeyByHash string
+	insertKey      string
+	revokeKey      string
+
+	// Returned when a key is unknown or revoked.
+	ErrInvalidKey = errors.New("invalid API key")
+)
+
+// Prepare queries.
+func init() {
+	// Every key belongs to a registered app, load it along.
+	queryKeyByHash = fmt.Sprintf(
+		"SELECT k.id, k.app, k.username, %s FROM %s k JOIN %s a ON a.name = k.app "+
+			"WHERE k.key_hash = $1 AND k.revoked_at IS NULL",
+		apps.Columns("a"), apiKeyTableName, appTableName)
+
+	insertKey = fmt.Sprintf(
+		"INSERT INTO %s (app, username, name, key_hash) VALUES ($1, $2, $3, $4) RETURNING id",
+		apiKeyTableName)
+
+	revokeKey = fmt.Sprintf(
+		"UPDATE %s SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL",
+		apiKeyTableName)
+}
+
+// The authenticated caller. Keys are issued per app; a key may additionally
+// be bound to one user of the app, otherwise it acts for any user of the app
+// (that's what chat adapters use).
+type Identity struct {
+	KeyID    int64
+	App      string
+	Username string
+
+	// Registration of App, it may be disabled.
+	Tenant *apps.App
+}
+
+// Issue a new key for the app, optionally bound to a user. Only the hash is
+// stored, the returned plain key can't be recovered later.
+func CreateKey(ctx context.Context, db *sql.DB, app string, username string, name string) (int64, string, error) {
+	if app == "" {
+		return 0, "", errors.New("app is required")
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return 0, "", err
+	}
+	key := keyPrefix + hex.EncodeToString(secret)
+
+	var id int64
+	err := db.QueryRowContext(ctx, insertKey, app, username, name, hashKey(key)).Scan(&id)
+	if err != nil {
+		return 0, "", err
+	}
+	return id, key, nil
+}
+
+// Revoke a key so it can no longer authenticate.
+func RevokeKey(ctx context.Context, db *sql.DB, id int64) error {
+	res, err := db.ExecContext(ctx, revokeKey, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// Look up the identity of a plain key, with its app.
+func Authenticate(ctx context.Context, db *sql.DB, key string) (*Identity, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	id := Identity{Tenant: &apps.App{}}
+	dest := append([]interface{}{&id.KeyID, &id.App, &id.Username}, id.Tenant.Fields()...)
+	err := db.QueryRowContext(ctx, queryKeyByHash, hashKey(key)).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidKey
+	} else if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// Keys resolves plain keys to identities.
+type Keys interface {
+	Authenticate(ctx context.Context, key string) (*Identity, error)
+}
+
+type postgresKeys struct {
+	db *sql.DB
+}
+
+// Keys of the `api_key` table.
+func NewPostgresKeys(db *sql.DB) Keys {
+	return postgresKeys{db}
+}
+
+func (k postgresKeys) Authenticate(ctx context.Context, key string) (*Identity, error) {
+	return Authenticate(ctx, k.db, key)
+}
+
+// MemoryKeys maps plain keys to identities, for tests.
+type MemoryKeys map[string]Identity
+
+func (k MemoryKeys) Authenticate(ctx context.Context, key string) (*Identity, error) {
+	id, ok := k[key]
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return &id, nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey struct{}
+
+// Attach an identity to the context.
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Get the identity attached by Require, nil if none.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(contextKey{}).(*Identity)
+	return id
+}
+
+// Target names the app and user a request acts on, "" if it doesn't say.
+type Target func(r *http.Request) (app string, username string)
+
+// Target of the `username` form value, in whichever app owns the key.
+func FormTarget(r *http.Request) (string, string) {
+	return "", r.FormValue("username")
+}
+
+// Require wraps a handler so it only runs for requests carrying a valid key,
+// either as `Authorization: Bearer <key>` or in the `X-API-Key` header.
+//
+// The identity's username is resolved before calling the handler: keys bound
+// to a user always act as that user, app keys act as the user named in the
+// `username` form value.
+func Require(keys Keys, next http.HandlerFunc) http.HandlerFunc {
+	return RequireTarget(keys, FormTarget, next)
+}
+
+// Like Require, but the app and user come from target. Requests targeting
+// another app than the key's are forbidden.
+func RequireTarget(keys Keys, target Target, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := authenticateRequest(w, r, keys)
+		if !ok {
+			return
+		}
+
+		app, username := target(r)
+		if app != "" && app != id.App {
+			apierror.Write(w, r, apierror.Forbidden("key is not valid for this app"))
+			return
+		}
+		if id.Username == "" {
+			if username == "" {
+				apierror.Write(w, r, apierror.InvalidField("username", "is required"))
+				return
+			}
+			id.Username = username
+		} else if username != "" && username != id.Username {
+			apierror.Write(w, r, apierror.Forbidden("key is not valid for this user"))
+			return
+		}
+
+		logging.SetUser(r.Context(), id.App, id.Username)
+		next(w, r.WithContext(NewContext(r.Context(), id)))
+	}
+}
+
+// Like Require, for requests acting on the app itself rather than one of
+// its users. Only app keys of the app named by target are accepted.
+func RequireApp(keys Keys, target Target, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := authenticateRequest(w, r, keys)
+		if !ok {
+			return
+		}
+
+		if app, _ := target(r); app != "" && app != id.App {
+			apierror.Write(w, r, apierror.Forbidden("key is not valid for this app"))
+			return
+		}
+		if id.Username != "" {
+			apierror.Write(w, r, apierror.Forbidden("an app key is required"))
+			return
+		}
+
+		logging.SetUser(r.Context(), id.App, "")
+		next(w, r.WithContext(NewContext(r.Context(), id)))
+	}
+}
+
+// Like Require, for operator endpoints. The request must carry the given
+// admin token instead of an API key; without a token configured the
+// endpoints are disabled.
+func RequireAdmin(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			apierror.Write(w, r, apierror.Forbidden("admin endpoints are disabled"))
+			return
+		}
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bot-admin"`)
+			apierror.Write(w, r, apierror.Unauthorized("admin token required"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Authenticate the key of a request, answering 401 if it's missing or
+// invalid.
+func authenticateRequest(w http.ResponseWriter, r *http.Request, keys Keys) (*Identity, bool) {
+	key := PresentedKey(r)
+	if key == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="bot"`)
+		apierror.Write(w, r, apierror.Unauthorized("API key required"))
+		return nil, false
+	}
+
+	id, err := keys.Authenticate(r.Context(), key)
+	if err == ErrInvalidKey {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="bot"`)
+		apierror.Write(w, r, apierror.Unauthorized("%v", err))
+		return nil, false
+	} else if err != nil {
+		apierror.Write(w, r, err)
+		return nil, false
+	}
+	return id, true
+}
+
+// The API key a request carries, "" if none. The bearer token wins over
+// the `X-API-Key` header.
+func PresentedKey(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	return r.Header.Get("X-API-Key")
+}
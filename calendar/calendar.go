@@ -0,0 +1,98 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const feedTableName = "calendar_feed"
+const appTableName = "app"
+
+// Prefix of feed tokens, they're not API keys.
+const tokenPrefix = "cal_"
+
+var (
+	queryFeedByHash string
+	upsertFeed      string
+	deleteFeed      string
+
+	// Returned when a token is unknown, revoked or of a disabled app.
+	ErrInvalidToken = errors.New("invalid calendar token")
+
+	// Returned when revoking the feed of a user who has none.
+	ErrFeedNotFound = errors.New("calendar feed not found")
+)
+
+// Prepare queries.
+func init() {
+	queryFeedByHash = fmt.Sprintf(
+		"SELECT f.app, f.username FROM %s f "+
+			"JOIN %s a ON a.name = f.app AND a.disabled_at IS NULL WHERE f.token_hash = $1",
+		feedTableName, appTableName)
+
+	// A user has one feed, creating another replaces its token.
+	upsertFeed = fmt.Sprintf(
+		"INSERT INTO %s (app, username, token_hash) VALUES ($1, $2, $3) "+
+			"ON CONFLICT (app, username) DO UPDATE "+
+			"SET token_hash = EXCLUDED.token_hash, created_at = now()",
+		feedTableName)
+
+	deleteFeed = fmt.Sprintf(
+		"DELETE FROM %s WHERE app = $1 AND username = $2", feedTableName)
+}
+
+// Issue the feed token of a user, revoking any previous one. Only the hash
+// is stored, the returned token can't be recovered later.
+func CreateToken(ctx context.Context, db *sql.DB, app string, username string) (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := tokenPrefix + hex.EncodeToString(secret)
+
+	if _, err := db.ExecContext(ctx, upsertFeed, app, username, hashToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Revoke the feed token of a user.
+func RevokeToken(ctx context.Context, db *sql.DB, app string, username string) error {
+	res, err := db.ExecContext(ctx, deleteFeed, app, username)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrFeedNotFound
+	}
+	return nil
+}
+
+// Look up the app and user a feed token was issued to.
+func Lookup(ctx context.Context, db *sql.DB, token string) (string, string, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return "", "", ErrInvalidToken
+	}
+
+	var app, username string
+	err := db.QueryRowContext(ctx, queryFeedByHash, hashToken(token)).Scan(&app, &username)
+	if err == sql.ErrNoRows {
+		return "", "", ErrInvalidToken
+	} else if err != nil {
+		return "", "", err
+	}
+	return app, username, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,153 @@
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/this-is-a-bot/bot/tracker"
+)
+
+// Days of completions a feed covers.
+const HistoryDays = 365
+
+// How often calendar apps should refetch the feed.
+const refreshInterval = "PT1H"
+
+// Feed of a user's habits, rendered as an iCalendar document.
+type Feed struct {
+	// Name of the calendar as shown by calendar apps.
+	Name string
+
+	// Timezone deciding which day an event falls on.
+	Location *time.Location
+
+	Catalogs []tracker.Catalog
+	Events   []tracker.HistoryEvent
+	Now      time.Time
+}
+
+// Render the feed: one all-day event per day a catalog was done, and a
+// to-do per catalog recurring daily from its next undone day.
+func (f *Feed) ICS() []byte {
+	w := &icsWriter{}
+	stamp := f.Now.UTC().Format("20060102T150405Z")
+
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:-//this-is-a-bot//tracker//EN")
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	w.line("X-WR-CALNAME:" + escapeText(f.Name))
+	w.line("X-WR-TIMEZONE:" + f.Location.String())
+	w.line("REFRESH-INTERVAL;VALUE=DURATION:" + refreshInterval)
+	w.line("X-PUBLISHED-TTL:" + refreshInterval)
+
+	catalogs := make(map[int]tracker.Catalog, len(f.Catalogs))
+	for _, catalog := range f.Catalogs {
+		catalogs[catalog.ID] = catalog
+	}
+
+	// Events come oldest first, the latest value of a day is kept like the
+	// tracking list shows it.
+	type completion struct {
+		catalog tracker.Catalog
+		day     time.Time
+		value   float64
+	}
+	var days []*completion
+	byDay := make(map[string]*completion)
+	for _, event := range f.Events {
+		catalog, ok := catalogs[event.CatalogID]
+		if !ok {
+			continue
+		}
+		day := civilDay(event.MarkedAt.In(f.Location))
+		key := fmt.Sprintf("%d-%s", catalog.ID, day.Format("20060102"))
+		if c, ok := byDay[key]; ok {
+			c.value = event.Value
+			continue
+		}
+		byDay[key] = &completion{catalog: catalog, day: day, value: event.Value}
+		days = append(days, byDay[key])
+	}
+
+	for _, c := range days {
+		date := c.day.Format("20060102")
+		w.line("BEGIN:VEVENT")
+		w.line(fmt.Sprintf("UID:catalog-%d-%s@this-is-a-bot", c.catalog.ID, date))
+		w.line("DTSTAMP:" + stamp)
+		w.line("DTSTART;VALUE=DATE:" + date)
+		w.line("DTEND;VALUE=DATE:" + c.day.AddDate(0, 0, 1).Format("20060102"))
+		w.line("SUMMARY:" + escapeText("✓ "+c.catalog.Name))
+		if c.value > 0 {
+			w.line("DESCRIPTION:" + escapeText(strings.TrimSpace(
+				fmt.Sprintf("%v %s", float32(c.value), c.catalog.Unit))))
+		}
+		w.line("TRANSP:TRANSPARENT")
+		w.line("END:VEVENT")
+	}
+
+	today := civilDay(f.Now.In(f.Location))
+	for _, catalog := range f.Catalogs {
+		next := today
+		if catalog.Done {
+			next = today.AddDate(0, 0, 1)
+		}
+		w.line("BEGIN:VTODO")
+		w.line(fmt.Sprintf("UID:catalog-%d@this-is-a-bot", catalog.ID))
+		w.line("DTSTAMP:" + stamp)
+		w.line("DTSTART;VALUE=DATE:" + next.Format("20060102"))
+		w.line("DUE;VALUE=DATE:" + next.AddDate(0, 0, 1).Format("20060102"))
+		w.line("RRULE:FREQ=DAILY")
+		w.line("SUMMARY:" + escapeText(catalog.Name))
+		if len(catalog.Tags) > 0 {
+			// Tags are plain names, commas here separate them.
+			w.line("CATEGORIES:" + strings.Join(catalog.Tags, ","))
+		}
+		w.line("STATUS:NEEDS-ACTION")
+		w.line("END:VTODO")
+	}
+
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+// Date of t as midnight UTC, so all-day dates don't move with DST.
+func civilDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Escape a TEXT value, RFC 5545 section 3.3.11. Any line break becomes \n,
+// a raw CR would end the content line.
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`,
+	).Replace(s)
+}
+
+// Writes content lines with CRLF endings, folded at 75 octets without
+// splitting UTF-8 sequences.
+type icsWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *icsWriter) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.buf.WriteString(s[:cut])
+		w.buf.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts.
+		limit = 74
+	}
+	w.buf.WriteString(s)
+	w.buf.WriteString("\r\n")
+}
@@ -0,0 +1,109 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/this-is-a-bot/bot/tracker"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := map[string]string{
+		"Read":            "Read",
+		`C:\habits`:       `C:\\habits`,
+		"run; swim, bike": `run\; swim\, bike`,
+		"two\r\nlines":    `two\nlines`,
+		"two\nlines":      `two\nlines`,
+		"two\rlines":      `two\nlines`,
+		"end\r":           `end\n`,
+		"\r\n\r\n":        `\n\n`,
+	}
+	for in, want := range tests {
+		if got := escapeText(in); got != want {
+			t.Errorf("escapeText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	tests := []string{
+		"SUMMARY:short",
+		"SUMMARY:" + strings.Repeat("a", 67),
+		"SUMMARY:" + strings.Repeat("a", 68),
+		"SUMMARY:" + strings.Repeat("a", 300),
+		"SUMMARY:" + strings.Repeat("✓ é ", 40),
+		"SUMMARY:" + strings.Repeat("日本語", 30),
+	}
+	for _, line := range tests {
+		w := &icsWriter{}
+		w.line(line)
+		out := w.buf.String()
+
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Errorf("%q doesn't end with CRLF", out)
+			continue
+		}
+		physical := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+		for i, p := range physical {
+			if len(p) > 75 {
+				t.Errorf("line %d of %q is %d octets long", i, line, len(p))
+			}
+			if i > 0 && !strings.HasPrefix(p, " ") {
+				t.Errorf("continuation %q doesn't start with a space", p)
+			}
+			if !utf8.ValidString(p) {
+				t.Errorf("line %q splits a UTF-8 sequence", p)
+			}
+		}
+		if unfolded := strings.Replace(strings.TrimSuffix(out, "\r\n"), "\r\n ", "", -1); unfolded != line {
+			t.Errorf("unfolded %q, want %q", unfolded, line)
+		}
+	}
+}
+
+func TestICS(t *testing.T) {
+	loc, err := time.LoadLocation("US/Pacific")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2017, 5, 3, 12, 0, 0, 0, loc)
+	feed := &Feed{
+		Name:     "alice's habits",
+		Location: loc,
+		Catalogs: []tracker.Catalog{
+			{ID: 1, Name: "Run, fast", Unit: "km", Done: true, Tags: []string{"health", "sport"}},
+		},
+		Events: []tracker.HistoryEvent{
+			// Late evening in Pacific time, already the next day in UTC.
+			{CatalogID: 1, Value: 3, MarkedAt: time.Date(2017, 5, 2, 22, 0, 0, 0, loc)},
+			{CatalogID: 1, Value: 5, MarkedAt: time.Date(2017, 5, 2, 23, 0, 0, 0, loc)},
+			{CatalogID: 2, Value: 1, MarkedAt: time.Date(2017, 5, 2, 23, 0, 0, 0, loc)},
+		},
+		Now: now,
+	}
+	ics := string(feed.ICS())
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:alice's habits\r\n",
+		"UID:catalog-1-20170502@this-is-a-bot\r\n",
+		"DTSTART;VALUE=DATE:20170502\r\n",
+		"DTEND;VALUE=DATE:20170503\r\n",
+		"SUMMARY:✓ Run\\, fast\r\n",
+		"DESCRIPTION:5 km\r\n",
+		"UID:catalog-1@this-is-a-bot\r\n",
+		// Done today, due again tomorrow.
+		"DTSTART;VALUE=DATE:20170504\r\n",
+		"CATEGORIES:health,sport\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("feed lacks %q:\n%s", want, ics)
+		}
+	}
+	if n := strings.Count(ics, "BEGIN:VEVENT"); n != 1 {
+		t.Errorf("feed has %d events, want 1 for the one day done", n)
+	}
+}
@@ -0,0 +1,103 @@
+// Package client is a Go client of the bot API. The operations and types in
+// client_gen.go are generated from the OpenAPI document, regenerate them
+// with `go generate` after changing it.
+package client
+
+//go:generate go run gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the bot API at BaseURL, authenticated with APIKey.
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// Create a client, e.g. New("https://bot.example.com", "bot_...").
+func New(baseURL string, apiKey string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// APIError is returned when the API answers with an error status.
+type APIError struct {
+	StatusCode int
+	RequestID  string
+	Code       string
+	Message    string
+	Details    map[string]string
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("bot API: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("bot API: %s (%s, status %d)", e.Message, e.Code, e.StatusCode)
+}
+
+// Send a request with an optional JSON body, decoding the JSON response
+// into out unless it's nil.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(js)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: res.StatusCode}
+		var envelope ErrorEnvelope
+		data, _ := ioutil.ReadAll(res.Body)
+		if json.Unmarshal(data, &envelope) == nil {
+			apiErr.RequestID = envelope.RequestID
+			apiErr.Code = envelope.Error.Code
+			apiErr.Message = envelope.Error.Message
+			apiErr.Details = envelope.Error.Details
+		}
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
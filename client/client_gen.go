@@ -0,0 +1,666 @@
+// Code generated by gen.go from the OpenAPI document; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// SteamGame as defined by the API spec.
+type SteamGame struct {
+	// Steam app ID, 0 if unknown.
+	AppID  int    `json:"appId"`
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	ImgSrc string `json:"imgSrc"`
+	// Review summary as shown by the store.
+	Review      string  `json:"review"`
+	PriceBefore float32 `json:"priceBefore"`
+	PriceNow    float32 `json:"priceNow"`
+	// e.g. -40%.
+	Discount string `json:"discount"`
+	CC       string `json:"cc"`
+	Currency string `json:"currency"`
+}
+
+// Review as defined by the API spec.
+type Review struct {
+	Summary string `json:"summary"`
+	Percent int    `json:"percent"`
+	Total   int    `json:"total"`
+}
+
+// PricePoint as defined by the API spec.
+type PricePoint struct {
+	PriceBefore float32   `json:"priceBefore"`
+	PriceNow    float32   `json:"priceNow"`
+	Discount    string    `json:"discount"`
+	Currency    string    `json:"currency"`
+	RecordedAt  time.Time `json:"recordedAt"`
+}
+
+// GameDetail as defined by the API spec.
+type GameDetail struct {
+	AppID  int    `json:"appId"`
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	ImgSrc string `json:"imgSrc"`
+	CC     string `json:"cc"`
+	// Current discount, null if not on sale.
+	Discount *SteamGame `json:"discount"`
+	// Featured categories listing the game.
+	Featured     []string     `json:"featured"`
+	Review       *Review      `json:"review"`
+	PriceHistory []PricePoint `json:"priceHistory"`
+}
+
+// Subscription as defined by the API spec.
+type Subscription struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	App      string `json:"app"`
+	// Deliver to this channel instead of the user.
+	Channel string `json:"channel,omitempty"`
+	// Defaults to the digest frequency of the app.
+	Frequency  string     `json:"frequency"`
+	Platform   string     `json:"platform,omitempty"`
+	CC         string     `json:"cc"`
+	MaxPrice   float32    `json:"maxPrice,omitempty"`
+	MinReview  int        `json:"minReview,omitempty"`
+	Format     string     `json:"format"`
+	LastSentAt *time.Time `json:"lastSentAt,omitempty"`
+}
+
+// Catalog as defined by the API spec.
+type Catalog struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Unit string `json:"unit,omitempty"`
+	// Whether it's marked done today.
+	Done bool `json:"done"`
+	// Today's value, if done.
+	Value float32 `json:"value,omitempty"`
+	// Tags grouping catalogs, sorted.
+	Tags []string `json:"tags"`
+}
+
+// TrackerSummary: Today's progress over the catalogs having some tags.
+type TrackerSummary struct {
+	// Tags the catalogs were filtered by.
+	Tags []string `json:"tags"`
+	// Catalogs having the tags.
+	Total int `json:"total"`
+	// Of them, those done today.
+	Done int `json:"done"`
+	// Whether nothing is left to do today.
+	AllDone bool `json:"allDone"`
+	// Catalogs not done yet, ordered by ID.
+	Pending []Catalog `json:"pending"`
+}
+
+// Heatmap: Events per day over whole weeks, like a GitHub contribution graph.
+type Heatmap struct {
+	// First day, a Sunday.
+	From string `json:"from"`
+	// Last day, today in the app's timezone.
+	To string `json:"to"`
+	// Events over all days.
+	Total int `json:"total"`
+	// Events on the busiest day.
+	Max int `json:"max"`
+	// Every day from the first to the last.
+	Days []HeatmapDay `json:"days"`
+}
+
+// HeatmapDay as defined by the API spec.
+type HeatmapDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// CalendarFeed: A user's iCalendar feed.
+type CalendarFeed struct {
+	// Feed token, only shown once.
+	Token string `json:"token"`
+	// URL to subscribe to from calendar apps.
+	URL string `json:"url"`
+}
+
+// CatalogRequest: Omitted fields are left unchanged by updates.
+type CatalogRequest struct {
+	Name *string `json:"name,omitempty"`
+	Unit *string `json:"unit,omitempty"`
+	// Replaces the catalog's tags: up to 20 of lowercase letters, digits, '-' and '_'.
+	Tags []string `json:"tags"`
+}
+
+// EventRequest as defined by the API spec.
+type EventRequest struct {
+	Value float64 `json:"value,omitempty"`
+}
+
+// TrackerEvent as defined by the API spec.
+type TrackerEvent struct {
+	Type      string `json:"type"`
+	Username  string `json:"username"`
+	App       string `json:"app"`
+	CatalogID int    `json:"catalogId"`
+	// New name, for added and updated.
+	Name string `json:"name,omitempty"`
+	// New unit, for added and updated.
+	Unit string `json:"unit,omitempty"`
+	// Marked value, for marked.
+	Value float64 `json:"value,omitempty"`
+	// New tags, for tagged.
+	Tags []string  `json:"tags,omitempty"`
+	At   time.Time `json:"at"`
+}
+
+// Checks: ok or failed, per dependency. Failure details are only logged.
+type Checks map[string]string
+
+// Error as defined by the API spec.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Problems of single fields, keyed by field name.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// ErrorEnvelope as defined by the API spec.
+type ErrorEnvelope struct {
+	Error     Error  `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Webhook as defined by the API spec.
+type Webhook struct {
+	ID  int64  `json:"id"`
+	App string `json:"app"`
+	// Absolute http(s) URL deliveries are posted to.
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Signing secret, only returned on registration.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Delivery as defined by the API spec.
+type Delivery struct {
+	ID        int64  `json:"id"`
+	WebhookID int64  `json:"webhookId"`
+	Event     string `json:"event"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	// HTTP status of the last attempt.
+	ResponseStatus int    `json:"responseStatus,omitempty"`
+	LastError      string `json:"lastError,omitempty"`
+	// When it's retried, if pending.
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	DeliveredAt   *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// WebhookPayload as defined by the API spec.
+type WebhookPayload struct {
+	Event string `json:"event"`
+	// App of the event, omitted for steam events.
+	App        string    `json:"app,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+	// A TrackerEvent for catalog events, a Streak for streak.broken, the region and new games for steam.discount.
+	Data map[string]interface{} `json:"data"`
+}
+
+// Streak as defined by the API spec.
+type Streak struct {
+	CatalogID int    `json:"catalogId"`
+	Username  string `json:"username"`
+	App       string `json:"app"`
+	Name      string `json:"name"`
+	// Days in a row the catalog was done.
+	Days     int       `json:"days"`
+	LastDone time.Time `json:"lastDone"`
+}
+
+// CronRun as defined by the API spec.
+type CronRun struct {
+	StartedAt time.Time `json:"startedAt"`
+	// Null while running.
+	FinishedAt *time.Time `json:"finishedAt"`
+	// Why the run failed, absent if it succeeded.
+	Error string `json:"error,omitempty"`
+	// Dyno which ran it.
+	Owner string `json:"owner"`
+}
+
+// CronTask as defined by the API spec.
+type CronTask struct {
+	Name string `json:"name"`
+	// Cron expression, in the tracker timezone.
+	Schedule  string    `json:"schedule"`
+	NextRunAt time.Time `json:"nextRunAt"`
+	// Null if it never ran.
+	LastRun *CronRun `json:"lastRun"`
+}
+
+// App as defined by the API spec.
+type App struct {
+	Name string `json:"name"`
+	// Timezone deciding when a tracking day starts for its users, the deployment's if absent.
+	Timezone string `json:"timezone,omitempty"`
+	// Frequency of digests subscribed without one.
+	DigestFrequency string `json:"digestFrequency"`
+	// Quota, 0 means unlimited.
+	MaxCatalogsPerUser int `json:"maxCatalogsPerUser"`
+	// Quota, 0 means unlimited.
+	MaxWebhooks int       `json:"maxWebhooks"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Set once disabled, keys of disabled apps are refused.
+	DisabledAt time.Time `json:"disabledAt,omitempty"`
+}
+
+// AppRequest: Omitted or null fields keep their value, or the default for new apps.
+type AppRequest struct {
+	// Required when registering, lowercase letters, digits, - or _.
+	Name               string  `json:"name,omitempty"`
+	Timezone           *string `json:"timezone,omitempty"`
+	DigestFrequency    *string `json:"digestFrequency,omitempty"`
+	MaxCatalogsPerUser *int    `json:"maxCatalogsPerUser,omitempty"`
+	MaxWebhooks        *int    `json:"maxWebhooks,omitempty"`
+	Disabled           *bool   `json:"disabled,omitempty"`
+}
+
+// GetHealth: Liveness, always 200 with the state of each dependency.
+func (c *Client) GetHealth(ctx context.Context) (Checks, error) {
+	path := "/healthz"
+	var out Checks
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetReadiness: Readiness, 503 if a dependency is down or the server is shutting down.
+func (c *Client) GetReadiness(ctx context.Context) (Checks, error) {
+	path := "/readyz"
+	var out Checks
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOpenAPI: This document.
+func (c *Client) GetOpenAPI(ctx context.Context) (map[string]interface{}, error) {
+	path := "/openapi.json"
+	var out map[string]interface{}
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCronTasks: List scheduled tasks and their last run.
+func (c *Client) ListCronTasks(ctx context.Context) ([]CronTask, error) {
+	path := "/v1/admin/cron"
+	var out []CronTask
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListApps: List registered apps.
+func (c *Client) ListApps(ctx context.Context) ([]App, error) {
+	path := "/v1/admin/apps"
+	var out []App
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateApp: Register an app.
+func (c *Client) CreateApp(ctx context.Context, body *AppRequest) (*App, error) {
+	path := "/v1/admin/apps"
+	var out App
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetApp: Get one app.
+func (c *Client) GetApp(ctx context.Context, app string) (*App, error) {
+	path := "/v1/admin/apps/" + url.PathEscape(fmt.Sprint(app))
+	var out App
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateApp: Change the settings or quotas of an app, or disable it.
+func (c *Client) UpdateApp(ctx context.Context, app string, body *AppRequest) (*App, error) {
+	path := "/v1/admin/apps/" + url.PathEscape(fmt.Sprint(app))
+	var out App
+	if err := c.do(ctx, "PATCH", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSteamDiscountsParams are the optional query parameters of ListSteamDiscounts.
+type ListSteamDiscountsParams struct {
+	CC       string
+	Currency string
+}
+
+// ListSteamDiscounts: List discounted games.
+func (c *Client) ListSteamDiscounts(ctx context.Context, params *ListSteamDiscountsParams) ([]SteamGame, error) {
+	path := "/v1/steam/discounts"
+	query := url.Values{}
+	if params != nil {
+		if params.CC != "" {
+			query.Set("cc", fmt.Sprint(params.CC))
+		}
+		if params.Currency != "" {
+			query.Set("currency", fmt.Sprint(params.Currency))
+		}
+	}
+	var out []SteamGame
+	if err := c.do(ctx, "GET", path, query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListSteamFeaturedParams are the optional query parameters of ListSteamFeatured.
+type ListSteamFeaturedParams struct {
+	Feature  string
+	CC       string
+	Currency string
+}
+
+// ListSteamFeatured: List featured games of a category.
+func (c *Client) ListSteamFeatured(ctx context.Context, params *ListSteamFeaturedParams) ([]SteamGame, error) {
+	path := "/v1/steam/featured"
+	query := url.Values{}
+	if params != nil {
+		if params.Feature != "" {
+			query.Set("feature", fmt.Sprint(params.Feature))
+		}
+		if params.CC != "" {
+			query.Set("cc", fmt.Sprint(params.CC))
+		}
+		if params.Currency != "" {
+			query.Set("currency", fmt.Sprint(params.Currency))
+		}
+	}
+	var out []SteamGame
+	if err := c.do(ctx, "GET", path, query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSteamGameParams are the optional query parameters of GetSteamGame.
+type GetSteamGameParams struct {
+	CC       string
+	Currency string
+}
+
+// GetSteamGame: Get details and price history of a game.
+func (c *Client) GetSteamGame(ctx context.Context, appID int, params *GetSteamGameParams) (*GameDetail, error) {
+	path := "/v1/steam/games/" + url.PathEscape(fmt.Sprint(appID))
+	query := url.Values{}
+	if params != nil {
+		if params.CC != "" {
+			query.Set("cc", fmt.Sprint(params.CC))
+		}
+		if params.Currency != "" {
+			query.Set("currency", fmt.Sprint(params.Currency))
+		}
+	}
+	var out GameDetail
+	if err := c.do(ctx, "GET", path, query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOwnApp: Get the settings and quotas of an app.
+func (c *Client) GetOwnApp(ctx context.Context, app string) (*App, error) {
+	path := "/v1/apps/" + url.PathEscape(fmt.Sprint(app))
+	var out App
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListDigests: List digest subscriptions of a user.
+func (c *Client) ListDigests(ctx context.Context, user string, app string) ([]Subscription, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/digests"
+	var out []Subscription
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateDigest: Subscribe a user to a digest.
+func (c *Client) CreateDigest(ctx context.Context, user string, app string, body *Subscription) (*Subscription, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/digests"
+	var out Subscription
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteDigest: Unsubscribe a user from a digest.
+func (c *Client) DeleteDigest(ctx context.Context, user string, app string, id int64) error {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/digests/" + url.PathEscape(fmt.Sprint(id))
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+// ListWebhooks: List webhooks of an app.
+func (c *Client) ListWebhooks(ctx context.Context, app string) ([]Webhook, error) {
+	path := "/v1/apps/" + url.PathEscape(fmt.Sprint(app)) + "/webhooks"
+	var out []Webhook
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateWebhook: Register a webhook.
+func (c *Client) CreateWebhook(ctx context.Context, app string, body *Webhook) (*Webhook, error) {
+	path := "/v1/apps/" + url.PathEscape(fmt.Sprint(app)) + "/webhooks"
+	var out Webhook
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWebhook: Stop delivering to a webhook.
+func (c *Client) DeleteWebhook(ctx context.Context, app string, id int64) error {
+	path := "/v1/apps/" + url.PathEscape(fmt.Sprint(app)) + "/webhooks/" + url.PathEscape(fmt.Sprint(id))
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+// ListWebhookDeliveriesParams are the optional query parameters of ListWebhookDeliveries.
+type ListWebhookDeliveriesParams struct {
+	Limit int
+}
+
+// ListWebhookDeliveries: List the latest deliveries of a webhook.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, app string, id int64, params *ListWebhookDeliveriesParams) ([]Delivery, error) {
+	path := "/v1/apps/" + url.PathEscape(fmt.Sprint(app)) + "/webhooks/" + url.PathEscape(fmt.Sprint(id)) + "/deliveries"
+	query := url.Values{}
+	if params != nil {
+		if params.Limit != 0 {
+			query.Set("limit", fmt.Sprint(params.Limit))
+		}
+	}
+	var out []Delivery
+	if err := c.do(ctx, "GET", path, query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCatalogsParams are the optional query parameters of ListCatalogs.
+type ListCatalogsParams struct {
+	Tag []string
+}
+
+// ListCatalogs: List tracking catalogs of a user with todays status.
+func (c *Client) ListCatalogs(ctx context.Context, user string, app string, params *ListCatalogsParams) ([]Catalog, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/catalogs"
+	query := url.Values{}
+	if params != nil {
+		for _, v := range params.Tag {
+			query.Add("tag", fmt.Sprint(v))
+		}
+	}
+	var out []Catalog
+	if err := c.do(ctx, "GET", path, query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateCatalog: Add a tracking catalog.
+func (c *Client) CreateCatalog(ctx context.Context, user string, app string, body *CatalogRequest) (*Catalog, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/catalogs"
+	var out Catalog
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCatalog: Get a tracking catalog.
+func (c *Client) GetCatalog(ctx context.Context, user string, app string, id int) (*Catalog, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/catalogs/" + url.PathEscape(fmt.Sprint(id))
+	var out Catalog
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCatalog: Rename a catalog or change its unit.
+func (c *Client) UpdateCatalog(ctx context.Context, user string, app string, id int, body *CatalogRequest) (*Catalog, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/catalogs/" + url.PathEscape(fmt.Sprint(id))
+	var out Catalog
+	if err := c.do(ctx, "PATCH", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteCatalog: Remove a catalog, its history is kept.
+func (c *Client) DeleteCatalog(ctx context.Context, user string, app string, id int) error {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/catalogs/" + url.PathEscape(fmt.Sprint(id))
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+// CreateEvent: Mark a catalog done for today.
+func (c *Client) CreateEvent(ctx context.Context, user string, app string, id int, body *EventRequest) (*Catalog, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/catalogs/" + url.PathEscape(fmt.Sprint(id)) + "/events"
+	var out Catalog
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCatalogHeatmapParams are the optional query parameters of GetCatalogHeatmap.
+type GetCatalogHeatmapParams struct {
+	Weeks int
+}
+
+// GetCatalogHeatmap: Get a heatmap of the days a catalog was marked.
+func (c *Client) GetCatalogHeatmap(ctx context.Context, user string, app string, id int, params *GetCatalogHeatmapParams) (*Heatmap, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/catalogs/" + url.PathEscape(fmt.Sprint(id)) + "/heatmap"
+	query := url.Values{}
+	if params != nil {
+		if params.Weeks != 0 {
+			query.Set("weeks", fmt.Sprint(params.Weeks))
+		}
+	}
+	var out Heatmap
+	if err := c.do(ctx, "GET", path, query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTrackerTodayParams are the optional query parameters of GetTrackerToday.
+type GetTrackerTodayParams struct {
+	Tag []string
+}
+
+// GetTrackerToday: Whether all catalogs having the tags are done today.
+func (c *Client) GetTrackerToday(ctx context.Context, user string, app string, params *GetTrackerTodayParams) (*TrackerSummary, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/today"
+	query := url.Values{}
+	if params != nil {
+		for _, v := range params.Tag {
+			query.Add("tag", fmt.Sprint(v))
+		}
+	}
+	var out TrackerSummary
+	if err := c.do(ctx, "GET", path, query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUserHeatmapParams are the optional query parameters of GetUserHeatmap.
+type GetUserHeatmapParams struct {
+	Tag   []string
+	Weeks int
+}
+
+// GetUserHeatmap: Get a heatmap of the days a user marked catalogs.
+func (c *Client) GetUserHeatmap(ctx context.Context, user string, app string, params *GetUserHeatmapParams) (*Heatmap, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/heatmap"
+	query := url.Values{}
+	if params != nil {
+		for _, v := range params.Tag {
+			query.Add("tag", fmt.Sprint(v))
+		}
+		if params.Weeks != 0 {
+			query.Set("weeks", fmt.Sprint(params.Weeks))
+		}
+	}
+	var out Heatmap
+	if err := c.do(ctx, "GET", path, query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCalendarFeed: Issue the token of the user's calendar feed.
+func (c *Client) CreateCalendarFeed(ctx context.Context, user string, app string) (*CalendarFeed, error) {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/calendar"
+	var out CalendarFeed
+	if err := c.do(ctx, "POST", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteCalendarFeed: Revoke the user's calendar feed.
+func (c *Client) DeleteCalendarFeed(ctx context.Context, user string, app string) error {
+	path := "/v1/users/" + url.PathEscape(fmt.Sprint(user)) + "/apps/" + url.PathEscape(fmt.Sprint(app)) + "/calendar"
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
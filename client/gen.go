@@ -0,0 +1,438 @@
+//go:build ignore
+// +build ignore
+
+// Generate client_gen.go from the OpenAPI document.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/this-is-a-bot/bot/openapi"
+)
+
+type schema struct {
+	Ref                  string         `json:"$ref"`
+	Type                 string         `json:"type"`
+	Format               string         `json:"format"`
+	Description          string         `json:"description"`
+	Nullable             bool           `json:"nullable"`
+	Required             []string       `json:"required"`
+	Properties           orderedSchemas `json:"properties"`
+	Items                *schema        `json:"items"`
+	AllOf                []*schema      `json:"allOf"`
+	AdditionalProperties *schema        `json:"additionalProperties"`
+	Enum                 []interface{}  `json:"enum"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type mediaTypes map[string]struct {
+	Schema *schema `json:"schema"`
+}
+
+type operation struct {
+	OperationID string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Deprecated  bool        `json:"deprecated"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content mediaTypes `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Ref     string     `json:"$ref"`
+		Content mediaTypes `json:"content"`
+	} `json:"responses"`
+}
+
+// Keys in document order, so generated code follows the spec.
+type orderedSchemas struct {
+	keys   []string
+	values map[string]*schema
+}
+
+func (o *orderedSchemas) UnmarshalJSON(data []byte) error {
+	keys, err := objectKeys(data)
+	if err != nil {
+		return err
+	}
+	o.keys = keys
+	return json.Unmarshal(data, &o.values)
+}
+
+// Top level keys of a JSON object, in order.
+func objectKeys(data []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+var initialisms = map[string]string{
+	"id": "ID", "cc": "CC", "url": "URL", "api": "API", "appid": "AppID",
+}
+
+// Go identifier of a JSON name, e.g. "request_id" -> "RequestID".
+func goName(name string) string {
+	var words []string
+	word := ""
+	for i, r := range name {
+		if r == '_' || r == '-' || r == '.' {
+			words, word = append(words, word), ""
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			words, word = append(words, word), ""
+		}
+		word += string(r)
+	}
+	words = append(words, word)
+
+	res := ""
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		if up, ok := initialisms[strings.ToLower(w)]; ok {
+			res += up
+		} else {
+			res += strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return res
+}
+
+// Go local variable name of a JSON name, e.g. "appid" -> "appID".
+func varName(name string) string {
+	n := goName(name)
+	for i, r := range n {
+		if i > 0 && unicode.IsLower(r) {
+			if i > 1 {
+				i--
+			}
+			return strings.ToLower(n[:i]) + n[i:]
+		}
+	}
+	return strings.ToLower(n)
+}
+
+// Schemas of the components, by name.
+var schemas map[string]*schema
+
+func refName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+// Go type of a schema.
+func goType(s *schema) string {
+	if s.Ref != "" {
+		return refName(s.Ref)
+	}
+	if len(s.AllOf) == 1 {
+		t := goType(s.AllOf[0])
+		if s.Nullable {
+			return "*" + t
+		}
+		return t
+	}
+
+	var t string
+	switch s.Type {
+	case "string":
+		t = "string"
+		if s.Format == "date-time" {
+			t = "time.Time"
+		}
+	case "integer":
+		t = "int"
+		if s.Format == "int64" {
+			t = "int64"
+		}
+	case "number":
+		t = "float64"
+		if s.Format == "float" {
+			t = "float32"
+		}
+	case "boolean":
+		t = "bool"
+	case "array":
+		return "[]" + goType(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + goType(s.AdditionalProperties)
+		}
+		return "map[string]interface{}"
+	default:
+		log.Fatalf("unsupported schema type %q", s.Type)
+	}
+	if s.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+func comment(buf *bytes.Buffer, text string) {
+	fmt.Fprintf(buf, "// %s\n", text)
+}
+
+func writeSchema(buf *bytes.Buffer, name string, s *schema) {
+	if s.Description != "" {
+		comment(buf, name+": "+s.Description)
+	} else {
+		comment(buf, name+" as defined by the API spec.")
+	}
+	if len(s.Properties.keys) == 0 {
+		fmt.Fprintf(buf, "type %s %s\n\n", name, goType(s))
+		return
+	}
+
+	required := make(map[string]bool)
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	fmt.Fprintf(buf, "type %s struct {\n", name)
+	for _, key := range s.Properties.keys {
+		prop := s.Properties.values[key]
+		if prop.Description != "" {
+			comment(buf, prop.Description)
+		}
+		// Nullable arrays are sent as null when nil, so an empty one isn't
+		// mistaken for an omitted one.
+		tag := key
+		if !required[key] && !(prop.Type == "array" && prop.Nullable) {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(buf, "%s %s `json:\"%s\"`\n", goName(key), goType(prop), tag)
+	}
+	fmt.Fprintf(buf, "}\n\n")
+}
+
+func writeOperation(buf *bytes.Buffer, method string, path string, pathParams []parameter, op *operation) {
+	name := goName(op.OperationID)
+	var params, query []parameter
+	params = append(params, pathParams...)
+	for _, p := range op.Parameters {
+		if p.In == "path" {
+			params = append(params, p)
+		} else if p.In == "query" {
+			query = append(query, p)
+		}
+	}
+
+	if len(query) > 0 {
+		comment(buf, fmt.Sprintf("%sParams are the optional query parameters of %s.", name, name))
+		fmt.Fprintf(buf, "type %sParams struct {\n", name)
+		for _, p := range query {
+			fmt.Fprintf(buf, "%s %s\n", goName(p.Name), goType(p.Schema))
+		}
+		fmt.Fprintf(buf, "}\n\n")
+	}
+
+	args := []string{"ctx context.Context"}
+	for _, p := range params {
+		args = append(args, varName(p.Name)+" "+goType(p.Schema))
+	}
+	if len(query) > 0 {
+		args = append(args, fmt.Sprintf("params *%sParams", name))
+	}
+	body := "nil"
+	if op.RequestBody != nil {
+		if mt, ok := op.RequestBody.Content["application/json"]; ok {
+			args = append(args, "body *"+goType(mt.Schema))
+			body = "body"
+		}
+	}
+
+	var result *schema
+	for _, status := range []string{"200", "201"} {
+		if res, ok := op.Responses[status]; ok {
+			result = res.Content["application/json"].Schema
+			break
+		}
+	}
+	// Structs are returned by pointer, slices and maps as they are.
+	ret, pointer := "error", false
+	if result != nil {
+		t := goType(result)
+		if result.Ref != "" && len(schemas[refName(result.Ref)].Properties.keys) > 0 {
+			t, pointer = "*"+t, true
+		}
+		ret = "(" + t + ", error)"
+	}
+
+	comment(buf, name+": "+op.Summary)
+	fmt.Fprintf(buf, "func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), ret)
+
+	// Build the path from literal segments and escaped parameters.
+	var parts []string
+	literal := ""
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		literal += "/"
+		if strings.HasPrefix(segment, "{") {
+			parts = append(parts, fmt.Sprintf("%q", literal))
+			literal = ""
+			p := varName(strings.Trim(segment, "{}"))
+			parts = append(parts, fmt.Sprintf("url.PathEscape(fmt.Sprint(%s))", p))
+			continue
+		}
+		literal += segment
+	}
+	if literal != "" {
+		parts = append(parts, fmt.Sprintf("%q", literal))
+	}
+	fmt.Fprintf(buf, "path := %s\n", strings.Join(parts, " + "))
+
+	queryArg := "nil"
+	if len(query) > 0 {
+		queryArg = "query"
+		fmt.Fprintf(buf, "query := url.Values{}\nif params != nil {\n")
+		for _, p := range query {
+			field := "params." + goName(p.Name)
+			// Arrays repeat the parameter, e.g. tag=a&tag=b.
+			if p.Schema.Type == "array" {
+				fmt.Fprintf(buf, "for _, v := range %s {\nquery.Add(%q, fmt.Sprint(v))\n}\n",
+					field, p.Name)
+				continue
+			}
+			fmt.Fprintf(buf, "if %s != %s {\nquery.Set(%q, fmt.Sprint(%s))\n}\n",
+				field, zeroValue(goType(p.Schema)), p.Name, field)
+		}
+		fmt.Fprintf(buf, "}\n")
+	}
+
+	upper := strings.ToUpper(method)
+	if result == nil {
+		fmt.Fprintf(buf, "return c.do(ctx, %q, path, %s, %s, nil)\n}\n\n", upper, queryArg, body)
+		return
+	}
+	fmt.Fprintf(buf, "var out %s\n", goType(result))
+	fmt.Fprintf(buf, "if err := c.do(ctx, %q, path, %s, %s, &out); err != nil {\nreturn nil, err\n}\n",
+		upper, queryArg, body)
+	if pointer {
+		fmt.Fprintf(buf, "return &out, nil\n}\n\n")
+	} else {
+		fmt.Fprintf(buf, "return out, nil\n}\n\n")
+	}
+}
+
+func zeroValue(t string) string {
+	switch t {
+	case "string":
+		return `""`
+	case "bool":
+		return "false"
+	case "int", "int64", "float32", "float64":
+		return "0"
+	}
+	return "nil"
+}
+
+func main() {
+	var spec struct {
+		Paths      json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas orderedSchemas `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal([]byte(openapi.Spec), &spec); err != nil {
+		log.Fatal(err)
+	}
+	paths, err := objectKeys(spec.Paths)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var raw map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(spec.Paths, &raw); err != nil {
+		log.Fatal(err)
+	}
+	schemas = spec.Components.Schemas.values
+
+	buf := &bytes.Buffer{}
+	for _, name := range spec.Components.Schemas.keys {
+		writeSchema(buf, name, spec.Components.Schemas.values[name])
+	}
+
+	for _, path := range paths {
+		var pathParams []parameter
+		if data, ok := raw[path]["parameters"]; ok {
+			if err := json.Unmarshal(data, &pathParams); err != nil {
+				log.Fatal(err)
+			}
+		}
+		for _, method := range methods {
+			data, ok := raw[path][method]
+			if !ok {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(data, &op); err != nil {
+				log.Fatal(err)
+			}
+			if op.Deprecated || !isJSON(&op) {
+				continue
+			}
+			writeOperation(buf, method, path, pathParams, &op)
+		}
+	}
+
+	imports := []string{"context", "fmt", "net/url"}
+	if bytes.Contains(buf.Bytes(), []byte("time.Time")) {
+		imports = append(imports, "time")
+	}
+	header := "// Code generated by gen.go from the OpenAPI document; DO NOT EDIT.\n\n" +
+		"package client\n\nimport (\n\"" + strings.Join(imports, "\"\n\"") + "\"\n)\n\n"
+
+	src, err := format.Source(append([]byte(header), buf.Bytes()...))
+	if err != nil {
+		log.Fatalf("%v\n%s", err, buf.Bytes())
+	}
+	if err := ioutil.WriteFile("client_gen.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Whether the operation answers JSON or nothing, the client skips text routes.
+func isJSON(op *operation) bool {
+	statuses := make([]string, 0, len(op.Responses))
+	for status := range op.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		if status == "204" {
+			return true
+		}
+		if strings.HasPrefix(status, "2") {
+			_, ok := op.Responses[status].Content["application/json"]
+			return ok
+		}
+	}
+	return false
+}
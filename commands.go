@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/this-is-a-bot/bot/apps"
+	"github.com/this-is-a-bot/bot/auth"
+	"github.com/this-is-a-bot/bot/config"
+	"github.com/this-is-a-bot/bot/migrate"
+	"github.com/this-is-a-bot/bot/queue"
+	"github.com/this-is-a-bot/bot/redis"
+	"github.com/this-is-a-bot/bot/steam"
+	"github.com/this-is-a-bot/bot/tracker"
+)
+
+const usage = `usage: bot [-config file] [command]
+
+Without a command the bot serves HTTP. Commands:
+  worker                                run queued jobs and scheduled tasks
+  migrate [up | down [n] | status]      change or show the database schema
+  jobs [stats | dead [n] | retry <id>]  inspect the job queue
+  user list [-app app]                  list tracker users
+  tracker export -user name [-app app] [-format json|csv]
+                                        dump a user's catalogs and events
+  steam ingest [-cc us,gb]              fetch listings from the Steam store
+  cache flush                           drop cached steam listings
+  app list                              list registered apps
+  app create|update <name> [-timezone tz] [-digest-frequency daily|weekly]
+      [-max-catalogs n] [-max-webhooks n]
+  app disable|enable <name>             register or change apps
+  apikey create -app app [-user name] [-name label]
+  apikey revoke <id>                    issue or revoke API keys`
+
+// Run an operations command, anything but the worker. Output meant for
+// people goes to the log, data to stdout.
+func runCommand(cfg *config.Config, db *sql.DB, rs redis.RedisStore, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(db, args[1:])
+	case "jobs":
+		return runJobs(newServer(cfg, db, rs).jobs, args[1:])
+	case "user":
+		return runUser(db, args[1:])
+	case "tracker":
+		return runTracker(db, args[1:])
+	case "steam":
+		return runSteam(newServer(cfg, db, rs).steam, args[1:])
+	case "cache":
+		return runCache(db, rs, args[1:])
+	case "app":
+		return runApp(db, args[1:])
+	case "apikey":
+		return runAPIKey(db, args[1:])
+	case "help", "-h", "--help":
+		fmt.Fprintln(os.Stdout, usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+}
+
+// Parse the flags of a subcommand, returning its remaining arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(ioutil.Discard)
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%s: %v", fs.Name(), err)
+	}
+	return fs.Args(), nil
+}
+
+// Run `bot migrate [up | down [n] | status]`.
+func runMigrate(db *sql.DB, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrate.Up(db)
+		for _, m := range applied {
+			log.Printf("Applied migration %d (%s)\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Println("Schema is up to date")
+		}
+		return err
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		reverted, err := migrate.Down(db, n)
+		for _, m := range reverted {
+			log.Printf("Reverted migration %d (%s)\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrate.GetStatus(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(os.Stdout, "%4d %-40s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate action %q, use up, down or status", action)
+}
+
+// Run `bot jobs [stats | dead [n] | retry <id>]`.
+func runJobs(jobs *queue.Queue, args []string) error {
+	action := "stats"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "stats":
+		stats, err := jobs.Stats()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(
+			os.Stdout, "ready %d, delayed %d, in flight %d, dead %d\n",
+			stats.Ready, stats.Delayed, stats.InFlight, stats.Dead)
+		return nil
+	case "dead":
+		n := 20
+		if len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of jobs %q", args[1])
+			}
+		}
+		dead, err := jobs.DeadJobs(context.Background(), n)
+		if err != nil {
+			return err
+		}
+		for _, job := range dead {
+			fmt.Fprintf(
+				os.Stdout, "%s %-20s %s %s\n", job.ID, job.Type,
+				job.EnqueuedAt.Format("2006-01-02 15:04:05"), job.LastError)
+		}
+		return nil
+	case "retry":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: bot jobs retry <id>")
+		}
+		if err := jobs.RetryDead(context.Background(), args[1]); err != nil {
+			return err
+		}
+		log.Printf("Job %s queued again\n", args[1])
+		return nil
+	}
+	return fmt.Errorf("unknown jobs action %q, use stats, dead or retry", action)
+}
+
+// Run `bot user list [-app app]`.
+func runUser(db *sql.DB, args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("unknown user action, use list")
+	}
+	fs := flag.NewFlagSet("user list", flag.ContinueOnError)
+	app := fs.String("app", "", "only users of this app")
+	if _, err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	users, err := tracker.GetUsers(context.Background(), db, *app)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		lastActive := "never"
+		if u.LastActive != nil {
+			lastActive = u.LastActive.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(os.Stdout, "%-20s %-30s %3d catalogs, last active %s\n",
+			u.App, u.Username, u.Catalogs, lastActive)
+	}
+	return nil
+}
+
+// Run `bot tracker export -user name [-app app] [-format json|csv]`.
+func runTracker(db *sql.DB, args []string) error {
+	if len(args) == 0 || args[0] != "export" {
+		return fmt.Errorf("unknown tracker action, use export")
+	}
+	fs := flag.NewFlagSet("tracker export", flag.ContinueOnError)
+	username := fs.String("user", "", "user to export")
+	app := fs.String("app", "", "only catalogs of this app")
+	format := fs.String("format", "json", "json or csv")
+	if _, err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("tracker export: -user is required")
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("tracker export: unknown format %q", *format)
+	}
+
+	catalogs, err := tracker.Export(context.Background(), db, *username, *app)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(catalogs)
+	}
+
+	// One row per event, catalogs without any get a row without value.
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"app", "catalog_id", "catalog", "unit", "removed", "value", "marked_at"})
+	for _, c := range catalogs {
+		row := []string{
+			c.App, strconv.Itoa(c.ID), c.Name, c.Unit, strconv.FormatBool(c.Removed), "", ""}
+		if len(c.Events) == 0 {
+			w.Write(row)
+		}
+		for _, e := range c.Events {
+			row[5] = strconv.FormatFloat(float64(e.Value), 'f', -1, 32)
+			row[6] = e.MarkedAt.Format(time.RFC3339)
+			w.Write(row)
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Run `bot steam ingest [-cc us,gb]`.
+func runSteam(store steam.Store, args []string) error {
+	if len(args) == 0 || args[0] != "ingest" {
+		return fmt.Errorf("unknown steam action, use ingest")
+	}
+	fs := flag.NewFlagSet("steam ingest", flag.ContinueOnError)
+	countries := fs.String("cc", steam.DefaultCountryCode, "comma separated country codes")
+	if _, err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	ingester := steam.NewIngester(store)
+	for _, cc := range strings.Split(*countries, ",") {
+		counts, err := ingester.Ingest(context.Background(), strings.TrimSpace(cc))
+		if err != nil {
+			return fmt.Errorf("steam ingest %s: %v", cc, err)
+		}
+		listings := make([]string, 0, len(counts))
+		for listing, n := range counts {
+			listings = append(listings, fmt.Sprintf("%s %d", listing, n))
+		}
+		sort.Strings(listings)
+		log.Printf("Ingested %s: %s\n", cc, strings.Join(listings, ", "))
+	}
+	return nil
+}
+
+// Run `bot cache flush`.
+func runCache(db *sql.DB, rs redis.RedisStore, args []string) error {
+	if len(args) == 0 || args[0] != "flush" {
+		return fmt.Errorf("unknown cache action, use flush")
+	}
+	cache := steam.NewCachedStore(steam.NewPostgresStore(db), rs, 0)
+	if err := cache.Invalidate(); err != nil {
+		return err
+	}
+	log.Println("Steam cache flushed")
+	return nil
+}
+
+// Run `bot apikey create -app app [-user name] [-name label]` or
+// `bot apikey revoke <id>`.
+func runAPIKey(db *sql.DB, args []string) error {
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		app := fs.String("app", "", "app the key is for")
+		username := fs.String("user", "", "bind the key to this user")
+		name := fs.String("name", "", "label to recognize the key by")
+		if _, err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		if _, err := apps.Get(context.Background(), db, *app); err != nil {
+			return fmt.Errorf("apikey create: app %q: %v", *app, err)
+		}
+		id, key, err := auth.CreateKey(context.Background(), db, *app, *username, *name)
+		if err != nil {
+			return err
+		}
+		log.Printf("Created key %d, it can't be shown again\n", id)
+		fmt.Fprintln(os.Stdout, key)
+		return nil
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: bot apikey revoke <id>")
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid key ID %q", args[1])
+		}
+		if err := auth.RevokeKey(context.Background(), db, id); err != nil {
+			return err
+		}
+		log.Printf("Revoked key %d\n", id)
+		return nil
+	}
+	return fmt.Errorf("unknown apikey action %q, use create or revoke", action)
+}
+
+// Run `bot app list`, `bot app create|update <name> [flags]` or
+// `bot app disable|enable <name>`.
+func runApp(db *sql.DB, args []string) error {
+	ctx := context.Background()
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "list":
+		list, err := apps.List(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, a := range list {
+			state := "enabled"
+			if !a.Enabled() {
+				state = "disabled"
+			}
+			timezone := a.Timezone
+			if timezone == "" {
+				timezone = "default timezone"
+			}
+			fmt.Fprintf(
+				os.Stdout, "%-20s %-8s %-20s %s digests, %d catalogs/user, %d webhooks\n",
+				a.Name, state, timezone, a.DigestFrequency, a.MaxCatalogsPerUser, a.MaxWebhooks)
+		}
+		return nil
+	case "create", "update", "disable", "enable":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: bot app %s <name>", action)
+		}
+	default:
+		return fmt.Errorf("unknown app action %q, use list, create, update, disable or enable", action)
+	}
+
+	a := &apps.App{Name: args[1]}
+	if action == "create" {
+		if err := apps.ValidateName(a.Name); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if a, err = apps.Get(ctx, db, args[1]); err != nil {
+			return err
+		}
+	}
+	switch action {
+	case "disable":
+		now := time.Now()
+		a.DisabledAt = &now
+	case "enable":
+		a.DisabledAt = nil
+	default:
+		// Only flags given on the command line change the app.
+		fs := flag.NewFlagSet("app "+action, flag.ContinueOnError)
+		fs.StringVar(&a.Timezone, "timezone", a.Timezone, "timezone of the app's users")
+		fs.StringVar(&a.DigestFrequency, "digest-frequency", a.DigestFrequency, "default digest frequency")
+		fs.IntVar(&a.MaxCatalogsPerUser, "max-catalogs", a.MaxCatalogsPerUser, "catalogs per user, 0 for unlimited")
+		fs.IntVar(&a.MaxWebhooks, "max-webhooks", a.MaxWebhooks, "webhooks, 0 for unlimited")
+		if _, err := parseFlags(fs, args[2:]); err != nil {
+			return err
+		}
+	}
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	if action == "create" {
+		if err := apps.Create(ctx, db, a); err != nil {
+			return err
+		}
+		log.Printf("Created app %s\n", a.Name)
+		return nil
+	}
+	if err := apps.Update(ctx, db, a); err != nil {
+		return err
+	}
+	log.Printf("Updated app %s\n", a.Name)
+	return nil
+}
@@ -0,0 +1,295 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Placeholder for secrets when printing the configuration.
+const redacted = "REDACTED"
+
+// Duration that reads from JSON as a string such as "5m".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Postgres connection pool sizes, zero means database/sql defaults.
+type DBConfig struct {
+	MaxOpenConns int `json:"maxOpenConns"`
+	MaxIdleConns int `json:"maxIdleConns"`
+}
+
+// Redis connection pool sizes, zero MaxActive means unlimited.
+type RedisConfig struct {
+	MaxIdle     int      `json:"maxIdle"`
+	MaxActive   int      `json:"maxActive"`
+	IdleTimeout Duration `json:"idleTimeout"`
+}
+
+// Config is everything the bot reads at startup.
+type Config struct {
+	DatabaseURL string      `json:"databaseUrl"`
+	RedisURL    string      `json:"redisUrl"`
+	Port        string      `json:"port"`
+	DB          DBConfig    `json:"db"`
+	Redis       RedisConfig `json:"redis"`
+
+	// Public URL of the bot, e.g. "https://bot.example.com", for links
+	// handed out to clients. Request headers can't be trusted for it.
+	BaseURL string `json:"baseUrl"`
+
+	// Timezone deciding when a tracker day starts.
+	Timezone string `json:"timezone"`
+
+	// How long steam listings stay cached.
+	CacheTTL Duration `json:"cacheTtl"`
+
+	// How long in-flight requests may take to finish on shutdown. Heroku
+	// kills a dyno 30 seconds after SIGTERM.
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+
+	// Jobs a worker process runs at once.
+	WorkerConcurrency int `json:"workerConcurrency"`
+
+	// Feature toggles, unknown features are off.
+	Features map[string]bool `json:"features"`
+
+	// Secrets of chat adapters and other integrations, keyed by name.
+	Secrets map[string]string `json:"secrets"`
+}
+
+// Defaults suitable for local development.
+func Default() *Config {
+	return &Config{
+		DatabaseURL: "dbname=bot sslmode=disable",
+		RedisURL:    "redis://127.0.0.1:6379",
+		Port:        "8080",
+		BaseURL:     "http://localhost:8080",
+		Redis: RedisConfig{
+			MaxIdle:     3,
+			IdleTimeout: Duration{240 * time.Second},
+		},
+		Timezone: "US/Pacific",
+		CacheTTL: Duration{5 * time.Minute},
+
+		ShutdownTimeout:   Duration{25 * time.Second},
+		WorkerConcurrency: 4,
+		Features: map[string]bool{
+			"digests": true, "webhooks": true, "auto_migrate": false,
+			"inline_worker": false, "steam_ingest": false,
+		},
+		Secrets: map[string]string{},
+	}
+}
+
+// Load the configuration: defaults, then the JSON file at path (if any), then
+// environment overrides. The result is validated.
+//
+// Recognized environment variables are DATABASE_URL, REDIS_URL and PORT (as
+// set by Heroku), BOT_BASE_URL, BOT_DB_MAX_OPEN_CONNS, BOT_DB_MAX_IDLE_CONNS,
+// BOT_REDIS_MAX_IDLE, BOT_REDIS_MAX_ACTIVE, BOT_REDIS_IDLE_TIMEOUT,
+// BOT_TIMEZONE, BOT_CACHE_TTL, BOT_SHUTDOWN_TIMEOUT, BOT_WORKER_CONCURRENCY,
+// BOT_FEATURE_<NAME> and BOT_SECRET_<NAME>.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		err = json.NewDecoder(f).Decode(cfg)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %v", path, err)
+		}
+		// A null in the file clears the map, environment overrides still
+		// need one.
+		if cfg.Features == nil {
+			cfg.Features = map[string]bool{}
+		}
+		if cfg.Secrets == nil {
+			cfg.Secrets = map[string]string{}
+		}
+	}
+
+	if err := cfg.applyEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) applyEnv(environ []string) error {
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			continue
+		}
+		name, value := kv[:i], kv[i+1:]
+
+		var err error
+		switch {
+		case name == "DATABASE_URL":
+			cfg.DatabaseURL = value
+		case name == "REDIS_URL":
+			cfg.RedisURL = value
+		case name == "PORT":
+			cfg.Port = value
+		case name == "BOT_BASE_URL":
+			cfg.BaseURL = value
+		case name == "BOT_DB_MAX_OPEN_CONNS":
+			cfg.DB.MaxOpenConns, err = strconv.Atoi(value)
+		case name == "BOT_DB_MAX_IDLE_CONNS":
+			cfg.DB.MaxIdleConns, err = strconv.Atoi(value)
+		case name == "BOT_REDIS_MAX_IDLE":
+			cfg.Redis.MaxIdle, err = strconv.Atoi(value)
+		case name == "BOT_REDIS_MAX_ACTIVE":
+			cfg.Redis.MaxActive, err = strconv.Atoi(value)
+		case name == "BOT_REDIS_IDLE_TIMEOUT":
+			cfg.Redis.IdleTimeout.Duration, err = time.ParseDuration(value)
+		case name == "BOT_TIMEZONE":
+			cfg.Timezone = value
+		case name == "BOT_CACHE_TTL":
+			cfg.CacheTTL.Duration, err = time.ParseDuration(value)
+		case name == "BOT_SHUTDOWN_TIMEOUT":
+			cfg.ShutdownTimeout.Duration, err = time.ParseDuration(value)
+		case name == "BOT_WORKER_CONCURRENCY":
+			cfg.WorkerConcurrency, err = strconv.Atoi(value)
+		case strings.HasPrefix(name, "BOT_FEATURE_"):
+			feature := strings.ToLower(strings.TrimPrefix(name, "BOT_FEATURE_"))
+			cfg.Features[feature], err = strconv.ParseBool(value)
+		case strings.HasPrefix(name, "BOT_SECRET_"):
+			cfg.Secrets[strings.ToLower(strings.TrimPrefix(name, "BOT_SECRET_"))] = value
+		}
+		if err != nil {
+			return fmt.Errorf("environment variable %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Check that the configuration is usable.
+func (cfg *Config) Validate() error {
+	var problems []string
+	if cfg.DatabaseURL == "" {
+		problems = append(problems, "databaseUrl is required")
+	}
+	if u, err := url.Parse(cfg.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+		problems = append(problems, "redisUrl must be a redis:// URL")
+	}
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port <= 0 || port > 65535 {
+		problems = append(problems, "port must be a TCP port number")
+	}
+	if u, err := url.Parse(cfg.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		problems = append(problems, "baseUrl must be an absolute http(s) URL")
+	}
+	if cfg.DB.MaxOpenConns < 0 || cfg.DB.MaxIdleConns < 0 {
+		problems = append(problems, "db pool sizes must not be negative")
+	}
+	if cfg.Redis.MaxIdle < 0 || cfg.Redis.MaxActive < 0 || cfg.Redis.IdleTimeout.Duration < 0 {
+		problems = append(problems, "redis pool settings must not be negative")
+	}
+	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		problems = append(problems, fmt.Sprintf("timezone: %v", err))
+	}
+	if cfg.CacheTTL.Duration <= 0 {
+		problems = append(problems, "cacheTtl must be positive")
+	}
+	if cfg.ShutdownTimeout.Duration <= 0 {
+		problems = append(problems, "shutdownTimeout must be positive")
+	}
+	if cfg.WorkerConcurrency <= 0 {
+		problems = append(problems, "workerConcurrency must be positive")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Absolute URL of a path on the bot, path starts with a slash.
+func (cfg *Config) URL(path string) string {
+	return strings.TrimRight(cfg.BaseURL, "/") + path
+}
+
+// Whether a feature toggle is on.
+func (cfg *Config) Enabled(feature string) bool {
+	return cfg.Features[feature]
+}
+
+// A copy of the configuration with passwords and secrets masked.
+func (cfg *Config) Redacted() *Config {
+	res := *cfg
+	res.DatabaseURL = redactDSN(cfg.DatabaseURL)
+	res.RedisURL = redactURL(cfg.RedisURL)
+	res.Secrets = make(map[string]string, len(cfg.Secrets))
+	for name, secret := range cfg.Secrets {
+		if secret != "" {
+			secret = redacted
+		}
+		res.Secrets[name] = secret
+	}
+	return &res
+}
+
+// The redacted configuration as JSON, safe to log.
+func (cfg *Config) String() string {
+	js, err := json.MarshalIndent(cfg.Redacted(), "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(js)
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
+
+// Postgres accepts both URLs and "key=value" connection strings.
+func redactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		return redactURL(dsn)
+	}
+	fields := strings.Fields(dsn)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "password=") {
+			fields[i] = "password=" + redacted
+		}
+	}
+	return strings.Join(fields, " ")
+}
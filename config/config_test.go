@@ -0,0 +1,37 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadNullMaps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(`{"databaseUrl": "postgres://localhost/bot", "features": null, "secrets": null}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("BOT_FEATURE_WEBHOOKS", "true")
+	os.Setenv("BOT_SECRET_ADMIN_TOKEN", "secret")
+	defer os.Unsetenv("BOT_FEATURE_WEBHOOKS")
+	defer os.Unsetenv("BOT_SECRET_ADMIN_TOKEN")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Enabled("webhooks") {
+		t.Error("webhooks feature not enabled from the environment")
+	}
+	if cfg.Secrets["admin_token"] != "secret" {
+		t.Errorf("admin_token = %q, want %q", cfg.Secrets["admin_token"], "secret")
+	}
+}
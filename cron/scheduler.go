@@ -0,0 +1,225 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/redis"
+)
+
+const (
+	lockKeyPrefix = "cron:lock:"
+	statusKey     = "cron:status"
+
+	// Tick locks only need to outlive the clock skew between dynos.
+	lockTTL = time.Hour
+)
+
+// Func is the work of a task.
+type Func func(ctx context.Context) error
+
+type task struct {
+	name     string
+	schedule *Schedule
+	run      Func
+}
+
+// Scheduler runs tasks declared with cron expressions. Every dyno may run
+// it: each tick of a task is claimed through a Redis lock, so exactly one
+// dyno runs it. Ticks passing while no scheduler runs are skipped.
+type Scheduler struct {
+	rs  redis.RedisStore
+	loc *time.Location
+
+	// Name of this process in run statuses.
+	owner string
+
+	tasks []*task
+}
+
+// Create a scheduler reading cron expressions in the given location.
+func New(rs redis.RedisStore, loc *time.Location) *Scheduler {
+	owner := os.Getenv("DYNO")
+	if owner == "" {
+		owner, _ = os.Hostname()
+	}
+	return &Scheduler{rs: rs, loc: loc, owner: owner}
+}
+
+// Declare a task. Names identify tasks across dynos and must be unique.
+func (s *Scheduler) Add(name string, spec string, run Func) error {
+	for _, t := range s.tasks {
+		if t.name == name {
+			return fmt.Errorf("cron task %q declared twice", name)
+		}
+	}
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	s.tasks = append(s.tasks, &task{name: name, schedule: schedule, run: run})
+	return nil
+}
+
+// Run tasks as they come due until ctx is done, then wait for running ones.
+func (s *Scheduler) Run(ctx context.Context) {
+	next := make(map[*task]time.Time, len(s.tasks))
+	now := time.Now().In(s.loc)
+	for _, t := range s.tasks {
+		next[t] = t.schedule.Next(now)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		var first time.Time
+		for _, at := range next {
+			if !at.IsZero() && (first.IsZero() || at.Before(first)) {
+				first = at
+			}
+		}
+		if first.IsZero() {
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(time.Until(first))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now().In(s.loc)
+		for _, t := range s.tasks {
+			tick := next[t]
+			if tick.IsZero() || tick.After(now) {
+				continue
+			}
+			next[t] = t.schedule.Next(now)
+
+			claimed, err := s.claim(t, tick)
+			if err != nil {
+				logging.Errorf(ctx, "failed to claim cron task %s: %v", t.name, err)
+				continue
+			}
+			if !claimed {
+				continue
+			}
+			wg.Add(1)
+			go func(t *task) {
+				defer wg.Done()
+				s.execute(t)
+			}(t)
+		}
+	}
+}
+
+// Claim one tick of a task, false if another dyno did.
+func (s *Scheduler) claim(t *task, tick time.Time) (bool, error) {
+	conn := s.rs.GetConnection()
+	defer conn.Close()
+	key := fmt.Sprintf("%s%s:%d", lockKeyPrefix, t.name, tick.Unix())
+	_, err := redigo.String(conn.Do("SET", key, s.owner, "NX", "EX", int(lockTTL/time.Second)))
+	if err == redigo.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Run a claimed task, recording its status before and after. Tasks get
+// their own context so shutdown doesn't abort them halfway.
+func (s *Scheduler) execute(t *task) {
+	ctx := context.Background()
+	run := Run{StartedAt: time.Now(), Owner: s.owner}
+	s.record(ctx, t, run)
+
+	err := safely(ctx, t.run)
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	if err != nil {
+		run.Error = err.Error()
+		logging.Errorf(ctx, "cron task %s failed: %v", t.name, err)
+	}
+	s.record(ctx, t, run)
+}
+
+func safely(ctx context.Context, fn Func) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+func (s *Scheduler) record(ctx context.Context, t *task, run Run) {
+	js, err := json.Marshal(run)
+	if err == nil {
+		conn := s.rs.GetConnection()
+		_, err = conn.Do("HSET", statusKey, t.name, js)
+		conn.Close()
+	}
+	if err != nil {
+		logging.Errorf(ctx, "failed to record status of cron task %s: %v", t.name, err)
+	}
+}
+
+// One run of a task.
+type Run struct {
+	StartedAt time.Time `json:"startedAt"`
+
+	// Nil while running.
+	FinishedAt *time.Time `json:"finishedAt"`
+
+	// Empty if the run succeeded.
+	Error string `json:"error,omitempty"`
+
+	// Dyno, or host, which ran it.
+	Owner string `json:"owner"`
+}
+
+// State of a declared task.
+type Status struct {
+	Name      string    `json:"name"`
+	Schedule  string    `json:"schedule"`
+	NextRunAt time.Time `json:"nextRunAt"`
+
+	// Nil if it never ran.
+	LastRun *Run `json:"lastRun"`
+}
+
+// Get the state of every declared task, as recorded by whichever dynos ran
+// them.
+func (s *Scheduler) Status(ctx context.Context) ([]Status, error) {
+	conn := s.rs.GetConnection()
+	defer conn.Close()
+	runs, err := redigo.StringMap(conn.Do("HGETALL", statusKey))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(s.loc)
+	res := make([]Status, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		status := Status{Name: t.name, Schedule: t.schedule.String(), NextRunAt: t.schedule.Next(now)}
+		if js, ok := runs[t.name]; ok {
+			var run Run
+			if err := json.Unmarshal([]byte(js), &run); err != nil {
+				logging.Errorf(ctx, "status of cron task %s is corrupted: %v", t.name, err)
+			} else {
+				status.LastRun = &run
+			}
+		}
+		res = append(res, status)
+	}
+	return res, nil
+}
@@ -0,0 +1,192 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/this-is-a-bot/bot/steam"
+)
+
+// The best deals for one subscription.
+type Digest struct {
+	Subscription Subscription      `json:"subscription"`
+	Games        []steam.SteamGame `json:"games"`
+	GeneratedAt  time.Time         `json:"generatedAt"`
+
+	// Games to compare the next digest against: the ones sent now and the
+	// ones seen before that are still on sale.
+	seen []int
+}
+
+// Chat card rendering of a single game.
+type Card struct {
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	ImageURL string `json:"imageUrl"`
+	Subtitle string `json:"subtitle"`
+	Text     string `json:"text,omitempty"`
+}
+
+// Build the digest of a subscription out of the current discounts in games,
+// keeping only games that are new since the last digest. A game that stays
+// on sale is sent once, one that leaves the sale and comes back is new
+// again.
+func Build(ctx context.Context, db *sql.DB, games steam.Store, sub Subscription, now time.Time) (*Digest, error) {
+	candidates, err := candidateGames(ctx, games, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	if sub.LastSentAt != nil {
+		if seen, err = seenAppIDs(ctx, db, sub.ID, *sub.LastSentAt); err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]steam.SteamGame, 0)
+	var stillSeen []int
+	for _, game := range candidates {
+		if !matches(sub, game) {
+			continue
+		}
+		if seen[game.AppID] {
+			stillSeen = append(stillSeen, game.AppID)
+			continue
+		}
+		res = append(res, game)
+	}
+
+	sort.Stable(byDiscount(res))
+	if len(res) > maxGames {
+		// Left out games aren't marked as seen, they're still new next time.
+		res = res[:maxGames]
+	}
+	for _, game := range res {
+		stillSeen = append(stillSeen, game.AppID)
+	}
+	return &Digest{Subscription: sub, Games: res, GeneratedAt: now, seen: stillSeen}, nil
+}
+
+// Discounted games for the subscription's region. With a platform, only
+// discounted games featured on that platform are considered.
+func candidateGames(ctx context.Context, games steam.Store, sub Subscription) ([]steam.SteamGame, error) {
+	discounts, err := games.GetDiscounts(ctx, sub.CountryCode)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Platform == "" {
+		return discounts, nil
+	}
+
+	featured, err := games.GetFeatured(ctx, sub.Platform, sub.CountryCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefer entries from the discount table, they carry review data.
+	byAppID := make(map[int]steam.SteamGame)
+	for _, game := range discounts {
+		byAppID[game.AppID] = game
+	}
+	res := make([]steam.SteamGame, 0)
+	for _, game := range featured {
+		if discounted, ok := byAppID[game.AppID]; ok {
+			res = append(res, discounted)
+		} else if game.PriceNow < game.PriceBefore {
+			res = append(res, game)
+		}
+	}
+	return res, nil
+}
+
+func matches(sub Subscription, game steam.SteamGame) bool {
+	if game.AppID == 0 {
+		return false
+	}
+	if sub.MaxPrice > 0 && game.PriceNow > sub.MaxPrice {
+		return false
+	}
+	if sub.MinReview > 0 {
+		review := steam.ParseReview(game.Review)
+		if review == nil || review.Percent < sub.MinReview {
+			return false
+		}
+	}
+	return true
+}
+
+// Games recorded at the subscription's digest of the given time.
+func seenAppIDs(ctx context.Context, db *sql.DB, subscriptionID int64, at time.Time) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, querySeenAppIDs, subscriptionID, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make(map[int]bool)
+	for rows.Next() {
+		var appID int
+		if err = rows.Scan(&appID); err != nil {
+			return nil, err
+		}
+		res[appID] = true
+	}
+	return res, rows.Err()
+}
+
+// Sort games by relative discount, best first.
+type byDiscount []steam.SteamGame
+
+func (a byDiscount) Len() int           { return len(a) }
+func (a byDiscount) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byDiscount) Less(i, j int) bool { return savings(a[i]) > savings(a[j]) }
+
+func savings(game steam.SteamGame) float32 {
+	if game.PriceBefore <= 0 {
+		return 0
+	}
+	return 1 - game.PriceNow/game.PriceBefore
+}
+
+// Plain text rendering, one game per line.
+func (d *Digest) Text() string {
+	if len(d.Games) == 0 {
+		return "No new Steam deals today."
+	}
+
+	lines := []string{
+		fmt.Sprintf("Best Steam deals (%s):", d.Subscription.Frequency),
+	}
+	for i, game := range d.Games {
+		line := fmt.Sprintf(
+			"%d. %s: %s (%s %.2f, was %.2f) %s", i+1, game.Name, game.Discount,
+			game.Currency, game.PriceNow, game.PriceBefore, game.URL)
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Chat card rendering, one card per game.
+func (d *Digest) Cards() []Card {
+	cards := make([]Card, 0, len(d.Games))
+	for _, game := range d.Games {
+		card := Card{
+			Title:    game.Name,
+			URL:      game.URL,
+			ImageURL: game.ImgSrc,
+			Subtitle: fmt.Sprintf(
+				"%s %.2f (%s, was %.2f)", game.Currency, game.PriceNow,
+				game.Discount, game.PriceBefore),
+		}
+		if review := steam.ParseReview(game.Review); review != nil {
+			card.Text = review.Summary
+		}
+		cards = append(cards, card)
+	}
+	return cards
+}
@@ -0,0 +1,140 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/steam"
+)
+
+// Deliverer sends a built digest to its subscriber, e.g. through a chat
+// adapter.
+type Deliverer interface {
+	Deliver(ctx context.Context, digest *Digest) error
+}
+
+// LogDeliverer writes digests to the standard logger, handy for local dev.
+type LogDeliverer struct{}
+
+func (LogDeliverer) Deliver(ctx context.Context, digest *Digest) error {
+	sub := digest.Subscription
+	logging.Printf(
+		ctx, "digest %d for %s/%s (channel %q):\n%s", sub.ID, sub.App, sub.Username,
+		sub.Channel, digest.Text())
+	return nil
+}
+
+// HTTPDeliverer posts digests as JSON to a chat adapter endpoint.
+type HTTPDeliverer struct {
+	URL string
+
+	// Client to post with, one with a 10s timeout if nil.
+	Client *http.Client
+}
+
+// Used without a Client, so a hung adapter can't block the digest task.
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Payload posted by HTTPDeliverer. Either Text or Cards is set, depending on
+// the subscription format.
+type Payload struct {
+	Username string `json:"username"`
+	App      string `json:"app"`
+	Channel  string `json:"channel,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Cards    []Card `json:"cards,omitempty"`
+}
+
+func (d *HTTPDeliverer) Deliver(ctx context.Context, digest *Digest) error {
+	sub := digest.Subscription
+	payload := Payload{Username: sub.Username, App: sub.App, Channel: sub.Channel}
+	if sub.Format == FormatCards {
+		payload.Cards = digest.Cards()
+	} else {
+		payload.Text = digest.Text()
+	}
+
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	client := d.Client
+	if client == nil {
+		client = defaultClient
+	}
+	req, err := http.NewRequest("POST", d.URL, bytes.NewReader(js))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("digest delivery failed with status %s", resp.Status)
+	}
+	return nil
+}
+
+// Build and deliver every subscription that is due. Subscriptions without
+// any new deal are skipped until their next period. Failing subscriptions
+// don't stop the others, the last error is returned.
+func RunDue(ctx context.Context, db *sql.DB, games steam.Store, deliverer Deliverer, now time.Time) error {
+	subs, err := querySubscriptions(ctx, db, queryDueSubscriptions, now)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, sub := range subs {
+		if err = deliverOne(ctx, db, games, deliverer, sub, now); err != nil {
+			logging.Errorf(ctx, "failed to deliver digest %d: %v", sub.ID, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func deliverOne(
+	ctx context.Context, db *sql.DB, games steam.Store, deliverer Deliverer,
+	sub Subscription, now time.Time) error {
+	digest, err := Build(ctx, db, games, sub, now)
+	if err != nil {
+		return err
+	}
+	if len(digest.Games) > 0 {
+		if err = deliverer.Deliver(ctx, digest); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, deleteSeenBefore, sub.ID, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, appID := range digest.seen {
+		if _, err = tx.ExecContext(ctx, insertSeen, sub.ID, appID, now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, updateLastSent, now, sub.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,202 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/this-is-a-bot/bot/steam"
+)
+
+const subscriptionTableName = "steam_digest_subscription"
+
+// Records the games on sale for a subscription at each digest, see Build.
+const sentTableName = "steam_digest_sent"
+
+// Supported digest frequencies and their period.
+var Frequencies = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+const (
+	FormatText  = "text"
+	FormatCards = "cards"
+)
+
+// Maximum number of games in one digest.
+const maxGames = 10
+
+var (
+	querySubscriptionsByUser string
+	queryDueSubscriptions    string
+	querySeenAppIDs          string
+	insertSubscription       string
+	deleteSubscription       string
+	insertSeen               string
+	deleteSeenBefore         string
+	updateLastSent           string
+
+	// Returned when unsubscribing from a subscription that doesn't exist.
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+// Prepare queries.
+func init() {
+	fields := []string{
+		"id", "username", "app", "channel", "frequency", "platform",
+		"country_code", "max_price", "min_review", "format", "last_sent_at",
+	}
+	querySubscriptionsByUser = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE username = $1 AND app = $2 ORDER BY id",
+		strings.Join(fields, ", "), subscriptionTableName)
+
+	// Let each frequency decide whether a subscription is due. Allow a few
+	// minutes of slack so a periodic runner doesn't keep drifting.
+	queryDueSubscriptions = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE last_sent_at IS NULL OR last_sent_at <= "+
+			"$1 - (CASE frequency WHEN 'weekly' THEN interval '7 days' ELSE interval '1 day' END) "+
+			"+ interval '10 minutes' ORDER BY id",
+		strings.Join(fields, ", "), subscriptionTableName)
+
+	querySeenAppIDs = fmt.Sprintf(
+		"SELECT app_id FROM %s WHERE subscription_id = $1 AND sent_at = $2",
+		sentTableName)
+
+	insertSubscription = fmt.Sprintf(
+		"INSERT INTO %s (username, app, channel, frequency, platform, "+
+			"country_code, max_price, min_review, format) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id",
+		subscriptionTableName)
+
+	deleteSubscription = fmt.Sprintf(
+		"DELETE FROM %s WHERE id = $1 AND username = $2 AND app = $3",
+		subscriptionTableName)
+
+	insertSeen = fmt.Sprintf(
+		"INSERT INTO %s (subscription_id, app_id, sent_at) VALUES ($1, $2, $3)",
+		sentTableName)
+
+	// Only the latest digest is compared against.
+	deleteSeenBefore = fmt.Sprintf(
+		"DELETE FROM %s WHERE subscription_id = $1 AND sent_at < $2",
+		sentTableName)
+
+	updateLastSent = fmt.Sprintf(
+		"UPDATE %s SET last_sent_at = $1 WHERE id = $2", subscriptionTableName)
+}
+
+// Corresponds to rows in `steam_digest_subscription` table. A subscription
+// belongs to a user of an app, and is delivered either to the user or to
+// the given channel.
+type Subscription struct {
+	ID          int64      `json:"id"`
+	Username    string     `json:"username"`
+	App         string     `json:"app"`
+	Channel     string     `json:"channel,omitempty"`
+	Frequency   string     `json:"frequency"`
+	Platform    string     `json:"platform,omitempty"`
+	CountryCode string     `json:"cc"`
+	MaxPrice    float32    `json:"maxPrice,omitempty"`
+	MinReview   int        `json:"minReview,omitempty"`
+	Format      string     `json:"format"`
+	LastSentAt  *time.Time `json:"lastSentAt,omitempty"`
+}
+
+// Check the subscription and fill in defaults.
+func (sub *Subscription) Validate() error {
+	if sub.Username == "" || sub.App == "" {
+		return errors.New("'username' and 'app' are required")
+	}
+	if sub.Frequency == "" {
+		sub.Frequency = "daily"
+	}
+	if _, ok := Frequencies[sub.Frequency]; !ok {
+		return errors.New("'frequency' must be daily or weekly")
+	}
+	switch sub.Platform {
+	case "", "win", "mac", "linux":
+	default:
+		return errors.New("'platform' must be win, mac or linux")
+	}
+	if !steam.IsValidCountryCode(sub.CountryCode) {
+		return errors.New("'cc' must be a two-letter country code")
+	}
+	if sub.CountryCode == "" {
+		sub.CountryCode = steam.DefaultCountryCode
+	}
+	if sub.MaxPrice < 0 {
+		return errors.New("'maxPrice' must not be negative")
+	}
+	if sub.MinReview < 0 || sub.MinReview > 100 {
+		return errors.New("'minReview' must be a percentage")
+	}
+	if sub.Format == "" {
+		sub.Format = FormatText
+	}
+	if sub.Format != FormatText && sub.Format != FormatCards {
+		return errors.New("'format' must be text or cards")
+	}
+	return nil
+}
+
+// Add a digest subscription, returning its ID.
+func Subscribe(ctx context.Context, db *sql.DB, sub Subscription) (int64, error) {
+	if err := sub.Validate(); err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err := db.QueryRowContext(
+		ctx, insertSubscription, sub.Username, sub.App, sub.Channel, sub.Frequency,
+		sub.Platform, sub.CountryCode, sub.MaxPrice, sub.MinReview,
+		sub.Format).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Remove a subscription of the given user.
+func Unsubscribe(ctx context.Context, db *sql.DB, id int64, username string, app string) error {
+	res, err := db.ExecContext(ctx, deleteSubscription, id, username, app)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// Get all subscriptions of the given user.
+func GetSubscriptions(ctx context.Context, db *sql.DB, username string, app string) ([]Subscription, error) {
+	return querySubscriptions(ctx, db, querySubscriptionsByUser, username, app)
+}
+
+func querySubscriptions(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]Subscription, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]Subscription, 0)
+	for rows.Next() {
+		var sub Subscription
+		err = rows.Scan(
+			&sub.ID, &sub.Username, &sub.App, &sub.Channel, &sub.Frequency,
+			&sub.Platform, &sub.CountryCode, &sub.MaxPrice, &sub.MinReview,
+			&sub.Format, &sub.LastSentAt)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, sub)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/this-is-a-bot/bot/apierror"
+	"github.com/this-is-a-bot/bot/apps"
+	"github.com/this-is-a-bot/bot/router"
+)
+
+// Body of app registration and updates. Omitted fields keep their value, or
+// the default for new apps.
+type appRequest struct {
+	Name               string  `json:"name"`
+	Timezone           *string `json:"timezone"`
+	DigestFrequency    *string `json:"digestFrequency"`
+	MaxCatalogsPerUser *int    `json:"maxCatalogsPerUser"`
+	MaxWebhooks        *int    `json:"maxWebhooks"`
+	Disabled           *bool   `json:"disabled"`
+}
+
+// Apply the fields set in the request to a.
+func (req *appRequest) apply(a *apps.App) {
+	if req.Timezone != nil {
+		a.Timezone = *req.Timezone
+	}
+	if req.DigestFrequency != nil {
+		a.DigestFrequency = *req.DigestFrequency
+	}
+	if req.MaxCatalogsPerUser != nil {
+		a.MaxCatalogsPerUser = *req.MaxCatalogsPerUser
+	}
+	if req.MaxWebhooks != nil {
+		a.MaxWebhooks = *req.MaxWebhooks
+	}
+	if req.Disabled != nil {
+		if !*req.Disabled {
+			a.DisabledAt = nil
+		} else if a.DisabledAt == nil {
+			now := time.Now()
+			a.DisabledAt = &now
+		}
+	}
+}
+
+// List the scheduled tasks with their last run, for operators.
+func (s *server) handleCronStatus(w http.ResponseWriter, r *http.Request) {
+	statuses, err := s.cron.Status(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, statuses)
+}
+
+// List all registered apps.
+func (s *server) handleListApps(w http.ResponseWriter, r *http.Request) {
+	list, err := apps.List(r.Context(), s.db)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, list)
+}
+
+// Register an app described by the JSON body.
+func (s *server) handleCreateApp(w http.ResponseWriter, r *http.Request) {
+	var req appRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	a := apps.App{Name: req.Name}
+	req.apply(&a)
+	if err := apps.ValidateName(a.Name); err != nil {
+		writeError(w, r, apierror.Invalid("%v", err))
+		return
+	}
+	if err := a.Validate(); err != nil {
+		writeError(w, r, apierror.Invalid("%v", err))
+		return
+	}
+	if err := apps.Create(r.Context(), s.db, &a); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", r.URL.Path+"/"+a.Name)
+	writeJSON(w, r, http.StatusCreated, a)
+}
+
+// Get one app.
+func (s *server) handleGetApp(w http.ResponseWriter, r *http.Request) {
+	a, err := apps.Get(r.Context(), s.db, router.Param(r, "app"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, a)
+}
+
+// Change the settings or quotas of an app, or disable it.
+func (s *server) handleUpdateApp(w http.ResponseWriter, r *http.Request) {
+	var req appRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	a, err := apps.Get(r.Context(), s.db, router.Param(r, "app"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	req.apply(a)
+	if err := a.Validate(); err != nil {
+		writeError(w, r, apierror.Invalid("%v", err))
+		return
+	}
+	if err := apps.Update(r.Context(), s.db, a); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, a)
+}
+
+// Get the settings and quotas of the caller's app.
+func (s *server) handleGetOwnApp(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, apps.FromContext(r.Context()))
+}
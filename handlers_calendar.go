@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/this-is-a-bot/bot/apierror"
+	"github.com/this-is-a-bot/bot/apps"
+	"github.com/this-is-a-bot/bot/auth"
+	"github.com/this-is-a-bot/bot/calendar"
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/router"
+	"github.com/this-is-a-bot/bot/tracker"
+)
+
+// A calendar feed as answered on creation, the token isn't shown again.
+type calendarFeed struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// Issue the calendar feed token of a user, replacing any previous one.
+func (s *server) handleCreateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	id := auth.FromContext(r.Context())
+	token, err := calendar.CreateToken(r.Context(), s.db, id.App, id.Username)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, calendarFeed{
+		Token: token,
+		URL:   s.cfg.URL("/v1/calendars/" + token + "/habits.ics"),
+	})
+}
+
+// Revoke the calendar feed token of a user.
+func (s *server) handleDeleteCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	id := auth.FromContext(r.Context())
+	if err := calendar.RevokeToken(r.Context(), s.db, id.App, id.Username); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Serve a user's habits as iCalendar. Calendar apps can't send API keys,
+// the token in the path is the credential.
+func (s *server) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	logging.SetPath(r.Context(), "/v1/calendars/REDACTED/habits.ics")
+	app, username, err := calendar.Lookup(r.Context(), s.db, router.Param(r, "token"))
+	if err == calendar.ErrInvalidToken {
+		writeError(w, r, apierror.NotFound("%v", err))
+		return
+	} else if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	a, err := apps.Get(r.Context(), s.db, app)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	logging.SetUser(r.Context(), app, username)
+	ctx := tracker.WithTimezone(r.Context(), a.Location())
+
+	catalogs, err := s.tracker.GetTrackingCatalogs(ctx, username, app)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	now := time.Now()
+	events, err := s.tracker.GetHistory(
+		ctx, username, app, now.AddDate(0, 0, -calendar.HistoryDays))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	feed := &calendar.Feed{
+		Name:     username + "'s habits",
+		Location: tracker.Timezone(ctx),
+		Catalogs: catalogs,
+		Events:   events,
+		Now:      now,
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="habits.ics"`)
+	w.Write(feed.ICS())
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/this-is-a-bot/bot/logging"
+)
+
+// Ping Postgres and Redis, returning the failure of each, nil if healthy.
+func (s *server) checkDependencies(ctx context.Context) map[string]error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	checks := make(map[string]error)
+	checks["postgres"] = s.db.PingContext(ctx)
+
+	conn := s.rs.GetConnection()
+	_, checks["redis"] = conn.Do("PING")
+	conn.Close()
+	return checks
+}
+
+// Write check results as JSON, with 503 if any failed and failing is set.
+// The endpoints are public, so failures are only detailed in the log.
+func writeChecks(w http.ResponseWriter, r *http.Request, checks map[string]error, failing bool) {
+	res := make(map[string]string)
+	status := http.StatusOK
+	for name, err := range checks {
+		res[name] = "ok"
+		if err != nil {
+			logging.Errorf(r.Context(), "%s check failed: %v", name, err)
+			res[name] = "failed"
+			if failing {
+				status = http.StatusServiceUnavailable
+			}
+		}
+	}
+
+	js, err := json.Marshal(res)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+// Liveness: report dependency checks but stay 200, restarting the dyno
+// doesn't fix a database outage.
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeChecks(w, r, s.checkDependencies(r.Context()), false)
+}
+
+// Readiness: 503 while shutting down or if a dependency is unreachable.
+func (s *server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := s.checkDependencies(r.Context())
+	if atomic.LoadInt32(&s.shuttingDown) == 1 {
+		checks["server"] = errors.New("shutting down")
+	}
+	writeChecks(w, r, checks, true)
+}
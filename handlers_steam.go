@@ -0,0 +1,257 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/this-is-a-bot/bot/apierror"
+	"github.com/this-is-a-bot/bot/apps"
+	"github.com/this-is-a-bot/bot/auth"
+	"github.com/this-is-a-bot/bot/digest"
+	"github.com/this-is-a-bot/bot/router"
+	"github.com/this-is-a-bot/bot/steam"
+)
+
+// Return a list of discounted steam games in JSON format.
+func (s *server) handleSteamDiscounts(w http.ResponseWriter, r *http.Request) {
+	cc := r.FormValue("cc")
+	if !steam.IsValidCountryCode(cc) {
+		writeError(w, r, apierror.InvalidField("cc", "must be a two-letter country code"))
+		return
+	}
+
+	games, err := s.steam.GetDiscounts(r.Context(), cc)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	s.writeSteamGames(w, r, games)
+}
+
+// Return a list of featured steam games in JSON format
+func (s *server) handleSteamFeatured(w http.ResponseWriter, r *http.Request) {
+	feature, cc := r.FormValue("feature"), r.FormValue("cc")
+	if !steam.IsValidCountryCode(cc) {
+		writeError(w, r, apierror.InvalidField("cc", "must be a two-letter country code"))
+		return
+	}
+
+	games, err := s.steam.GetFeatured(r.Context(), feature, cc)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	s.writeSteamGames(w, r, games)
+}
+
+// Return details of one steam game, keyed by app ID, in JSON format.
+func (s *server) handleSteamGame(w http.ResponseWriter, r *http.Request) {
+	appID, err := strconv.Atoi(router.Param(r, "appid"))
+	if err != nil || appID <= 0 {
+		writeError(w, r, apierror.Invalid("app ID must be a positive integer"))
+		return
+	}
+	cc := r.FormValue("cc")
+	if !steam.IsValidCountryCode(cc) {
+		writeError(w, r, apierror.InvalidField("cc", "must be a two-letter country code"))
+		return
+	}
+
+	detail, err := s.steam.GetGame(r.Context(), appID, cc)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if currency := r.FormValue("currency"); currency != "" {
+		rates, err := s.steam.GetExchangeRates(r.Context())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = rates.ConvertGameDetail(detail, currency)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	writeJSONWithETag(w, r, detail)
+}
+
+// Write games as JSON, converting prices first if the optional `currency`
+// parameter asks for it.
+func (s *server) writeSteamGames(w http.ResponseWriter, r *http.Request, games []steam.SteamGame) {
+	if currency := r.FormValue("currency"); currency != "" {
+		rates, err := s.steam.GetExchangeRates(r.Context())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = rates.ConvertGames(games, currency)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	writeJSONWithETag(w, r, games)
+}
+
+// Write v as JSON with an ETag, answering 304 if the client already has it.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	etag := fmt.Sprintf("\"%x\"", sha1.Sum(js))
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// Whether an If-None-Match header value matches the given strong ETag.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// List, add (POST) or remove (DELETE) digest subscriptions of a user.
+func (s *server) handleSteamDigests(w http.ResponseWriter, r *http.Request) {
+	id := auth.FromContext(r.Context())
+	username, app := id.Username, id.App
+	switch r.Method {
+	case "POST":
+		sub := digest.Subscription{
+			Channel:     r.PostFormValue("channel"),
+			Frequency:   r.PostFormValue("frequency"),
+			Platform:    r.PostFormValue("platform"),
+			CountryCode: r.PostFormValue("cc"),
+			Format:      r.PostFormValue("format"),
+		}
+		if text := r.PostFormValue("maxPrice"); text != "" {
+			maxPrice, err := strconv.ParseFloat(text, 32)
+			if err != nil {
+				writeError(w, r, apierror.InvalidField("maxPrice", "must be a float"))
+				return
+			}
+			sub.MaxPrice = float32(maxPrice)
+		}
+		if text := r.PostFormValue("minReview"); text != "" {
+			minReview, err := strconv.Atoi(text)
+			if err != nil {
+				writeError(w, r, apierror.InvalidField("minReview", "must be an integer"))
+				return
+			}
+			sub.MinReview = minReview
+		}
+		if _, ok := s.subscribe(w, r, sub); !ok {
+			return
+		}
+	case "DELETE":
+		id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+		if err != nil {
+			writeError(w, r, apierror.InvalidField("id", "must be an integer"))
+			return
+		}
+		if !s.unsubscribe(w, r, id) {
+			return
+		}
+	}
+
+	subs, err := digest.GetSubscriptions(r.Context(), s.db, username, app)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, subs)
+}
+
+// List digest subscriptions of a user.
+func (s *server) handleListDigests(w http.ResponseWriter, r *http.Request) {
+	id := auth.FromContext(r.Context())
+	subs, err := digest.GetSubscriptions(r.Context(), s.db, id.Username, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, subs)
+}
+
+// Subscribe a user to a digest described by the JSON body.
+func (s *server) handleCreateDigest(w http.ResponseWriter, r *http.Request) {
+	var sub digest.Subscription
+	if !readJSON(w, r, &sub) {
+		return
+	}
+	sub, ok := s.subscribe(w, r, sub)
+	if !ok {
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, sub.ID))
+	writeJSON(w, r, http.StatusCreated, sub)
+}
+
+// Unsubscribe a user from a digest.
+func (s *server) handleDeleteDigest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(router.Param(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, r, apierror.Invalid("subscription ID must be an integer"))
+		return
+	}
+	if s.unsubscribe(w, r, id) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Validate and save a subscription for the authenticated user, answering
+// the error if it fails.
+func (s *server) subscribe(w http.ResponseWriter, r *http.Request, sub digest.Subscription) (digest.Subscription, bool) {
+	id := auth.FromContext(r.Context())
+	sub.ID, sub.Username, sub.App, sub.LastSentAt = 0, id.Username, id.App, nil
+	sub.CountryCode = strings.ToLower(sub.CountryCode)
+	if a := apps.FromContext(r.Context()); a != nil && sub.Frequency == "" {
+		sub.Frequency = a.DigestFrequency
+	}
+	if err := sub.Validate(); err != nil {
+		writeError(w, r, apierror.Invalid("%v", err))
+		return sub, false
+	}
+
+	var err error
+	if sub.ID, err = digest.Subscribe(r.Context(), s.db, sub); err != nil {
+		writeError(w, r, err)
+		return sub, false
+	}
+	return sub, true
+}
+
+// Remove a subscription of the authenticated user, answering the error if
+// it fails.
+func (s *server) unsubscribe(w http.ResponseWriter, r *http.Request, subID int64) bool {
+	id := auth.FromContext(r.Context())
+	err := digest.Unsubscribe(r.Context(), s.db, subID, id.Username, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return false
+	}
+	return true
+}
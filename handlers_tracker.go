@@ -0,0 +1,492 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/this-is-a-bot/bot/apierror"
+	"github.com/this-is-a-bot/bot/apps"
+	"github.com/this-is-a-bot/bot/auth"
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/router"
+	"github.com/this-is-a-bot/bot/tracker"
+)
+
+/* Versioned JSON API. */
+
+// Body of catalog creation and updates. Omitted fields are left unchanged
+// by updates.
+type catalogRequest struct {
+	Name *string   `json:"name"`
+	Unit *string   `json:"unit"`
+	Tags *[]string `json:"tags"`
+}
+
+// Body of marking a catalog done, value is optional.
+type eventRequest struct {
+	Value float64 `json:"value"`
+}
+
+// List tracking catalogs of a user, with today's status. Repeated `tag`
+// parameters only keep catalogs having all of them.
+func (s *server) handleListCatalogs(w http.ResponseWriter, r *http.Request) {
+	tags, ok := tagFilter(w, r)
+	if !ok {
+		return
+	}
+	id := auth.FromContext(r.Context())
+	catalogs, err := s.tracker.GetTrackingCatalogs(r.Context(), id.Username, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, tracker.FilterByTags(catalogs, tags...))
+}
+
+// Whether the user is done today with every catalog having the `tag`
+// parameters, e.g. all their fitness habits.
+func (s *server) handleTrackerToday(w http.ResponseWriter, r *http.Request) {
+	tags, ok := tagFilter(w, r)
+	if !ok {
+		return
+	}
+	id := auth.FromContext(r.Context())
+	catalogs, err := s.tracker.GetTrackingCatalogs(r.Context(), id.Username, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, tracker.Summarize(catalogs, tags...))
+}
+
+// Heatmap of a user's events, of the catalogs having the `tag` parameters
+// if any. The path extension picks the format.
+func (s *server) handleUserHeatmap(w http.ResponseWriter, r *http.Request) {
+	tags, ok := tagFilter(w, r)
+	if !ok {
+		return
+	}
+	id := auth.FromContext(r.Context())
+	catalogs, err := s.tracker.GetTrackingCatalogs(r.Context(), id.Username, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	s.writeHeatmap(w, r, tracker.FilterByTags(catalogs, tags...), path.Ext(r.URL.Path))
+}
+
+// Heatmap of the events of one catalog.
+func (s *server) handleCatalogHeatmap(w http.ResponseWriter, r *http.Request) {
+	if catalog, ok := s.findCatalog(w, r); ok {
+		s.writeHeatmap(w, r, []tracker.Catalog{catalog}, path.Ext(r.URL.Path))
+	}
+}
+
+// Add a tracking catalog for a user.
+func (s *server) handleCreateCatalog(w http.ResponseWriter, r *http.Request) {
+	var req catalogRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Name == nil || *req.Name == "" {
+		writeError(w, r, apierror.InvalidField("name", "is required"))
+		return
+	}
+	catalog := tracker.Catalog{Name: *req.Name, Tags: make([]string, 0)}
+	if req.Unit != nil {
+		catalog.Unit = *req.Unit
+	}
+	if req.Tags != nil {
+		tags, err := tracker.NormalizeTags(*req.Tags)
+		if err != nil {
+			writeError(w, r, apierror.InvalidField("tags", err.Error()))
+			return
+		}
+		catalog.Tags = tags
+	}
+
+	catalogID, err := s.addCatalog(r.Context(), catalog.Name, catalog.Unit, catalog.Tags)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	catalog.ID = int(catalogID)
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, catalog.ID))
+	writeJSON(w, r, http.StatusCreated, catalog)
+}
+
+// Get one tracking catalog of a user.
+func (s *server) handleGetCatalog(w http.ResponseWriter, r *http.Request) {
+	if catalog, ok := s.findCatalog(w, r); ok {
+		writeJSON(w, r, http.StatusOK, catalog)
+	}
+}
+
+// Rename a tracking catalog, change its unit or replace its tags.
+func (s *server) handleUpdateCatalog(w http.ResponseWriter, r *http.Request) {
+	catalog, ok := s.findCatalog(w, r)
+	if !ok {
+		return
+	}
+	var req catalogRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Name != nil {
+		if *req.Name == "" {
+			writeError(w, r, apierror.InvalidField("name", "must not be empty"))
+			return
+		}
+		catalog.Name = *req.Name
+	}
+	if req.Unit != nil {
+		catalog.Unit = *req.Unit
+	}
+	var tags []string
+	if req.Tags != nil {
+		var err error
+		if tags, err = tracker.NormalizeTags(*req.Tags); err != nil {
+			writeError(w, r, apierror.InvalidField("tags", err.Error()))
+			return
+		}
+		catalog.Tags = tags
+	}
+
+	id := auth.FromContext(r.Context())
+	err := s.tracker.UpdateTracking(
+		r.Context(), id.Username, id.App, catalog.ID, catalog.Name, catalog.Unit, tags)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, catalog)
+}
+
+// Remove a tracking catalog, its history is kept.
+func (s *server) handleDeleteCatalog(w http.ResponseWriter, r *http.Request) {
+	catalogID, ok := catalogIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	id := auth.FromContext(r.Context())
+	err := s.tracker.RemoveTracking(r.Context(), id.Username, id.App, catalogID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Mark a catalog done, returning the catalog with its new status.
+func (s *server) handleCreateEvent(w http.ResponseWriter, r *http.Request) {
+	catalogID, ok := catalogIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req eventRequest
+	if r.ContentLength != 0 && !readJSON(w, r, &req) {
+		return
+	}
+
+	id := auth.FromContext(r.Context())
+	err := s.tracker.MarkDone(r.Context(), id.Username, id.App, catalogID, req.Value)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if catalog, ok := s.findCatalog(w, r); ok {
+		writeJSON(w, r, http.StatusCreated, catalog)
+	}
+}
+
+// Parse the `{id}` path parameter, answering 400 if it's invalid.
+func catalogIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	catalogID, err := strconv.Atoi(router.Param(r, "id"))
+	if err != nil {
+		writeError(w, r, apierror.Invalid("catalog ID must be an integer"))
+		return 0, false
+	}
+	return catalogID, true
+}
+
+// Parse the `tag` parameters filtering catalogs, answering 400 if one is
+// invalid.
+func tagFilter(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, apierror.Invalid("%v", err))
+		return nil, false
+	}
+	if len(r.Form["tag"]) == 0 {
+		return nil, true
+	}
+	tags, err := tracker.NormalizeTags(r.Form["tag"])
+	if err != nil {
+		writeError(w, r, apierror.InvalidField("tag", err.Error()))
+		return nil, false
+	}
+	return tags, true
+}
+
+// Write the heatmap of the catalogs over the `weeks` parameter, in the
+// format of a path extension: JSON without one, .svg, .png, or .txt for
+// Unicode blocks.
+func (s *server) writeHeatmap(w http.ResponseWriter, r *http.Request, catalogs []tracker.Catalog, ext string) {
+	weeks := tracker.DefaultHeatmapWeeks
+	if text := r.FormValue("weeks"); text != "" {
+		var err error
+		weeks, err = strconv.Atoi(text)
+		if err != nil || weeks <= 0 || weeks > tracker.MaxHeatmapWeeks {
+			writeError(w, r, apierror.InvalidField(
+				"weeks", fmt.Sprintf("must be between 1 and %d", tracker.MaxHeatmapWeeks)))
+			return
+		}
+	}
+
+	id := auth.FromContext(r.Context())
+	heatmap, err := tracker.GetHeatmap(
+		r.Context(), s.tracker, id.Username, id.App, catalogs, weeks, time.Now())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	switch ext {
+	case ".svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(heatmap.SVG())
+	case ".png":
+		img, err := heatmap.PNG()
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(img)
+	case ".txt":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(heatmap.Text()))
+	default:
+		writeJSON(w, r, http.StatusOK, heatmap)
+	}
+}
+
+// Get the catalog named by the path, answering the error if there's none.
+func (s *server) findCatalog(w http.ResponseWriter, r *http.Request) (tracker.Catalog, bool) {
+	catalogID, ok := catalogIDParam(w, r)
+	if !ok {
+		return tracker.Catalog{}, false
+	}
+
+	id := auth.FromContext(r.Context())
+	catalogs, err := s.tracker.GetTrackingCatalogs(r.Context(), id.Username, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return tracker.Catalog{}, false
+	}
+	for _, catalog := range catalogs {
+		if catalog.ID == catalogID {
+			return catalog, true
+		}
+	}
+	writeError(w, r, tracker.ErrCatalogNotFound)
+	return tracker.Catalog{}, false
+}
+
+// Add a tagged catalog for the authenticated user, within the quota of their
+// app.
+func (s *server) addCatalog(ctx context.Context, name string, unit string, tags []string) (int64, error) {
+	id := auth.FromContext(ctx)
+	max := 0
+	if a := apps.FromContext(ctx); a != nil {
+		max = a.MaxCatalogsPerUser
+	}
+	catalogID, err := s.tracker.AddTracking(ctx, id.Username, id.App, name, unit, tags, max)
+	if err == tracker.ErrTooManyCatalogs {
+		return 0, apierror.QuotaExceeded("app %s allows %d catalogs per user", id.App, max)
+	}
+	return catalogID, err
+}
+
+/* Streaming. */
+
+// Interval of SSE comments keeping idle streams open through proxies, the
+// Heroku router closes connections idle for 55 seconds.
+const streamKeepAlive = 15 * time.Second
+
+// Push the user's tracker events as Server-Sent Events until the client
+// goes away or the server shuts down.
+func (s *server) handleTrackerStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errors.New("streaming unsupported"))
+		return
+	}
+
+	id := auth.FromContext(r.Context())
+	events, stop := s.hub.Listen(id.Username, id.App)
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			fmt.Fprintf(w, ": keep-alive\n\n")
+		case event := <-events:
+			js, err := json.Marshal(event)
+			if err != nil {
+				logging.Errorf(r.Context(), "failed to encode tracker event: %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, js)
+		}
+		flusher.Flush()
+	}
+}
+
+/* Plain text API for chat adapters. */
+
+// Return plain texts of tracking list.
+func (s *server) handleTrackerListingText(w http.ResponseWriter, r *http.Request) {
+	s.writeTrackerListing(w, r)
+}
+
+// Add new tracking, then return plain texts of tracking list.
+func (s *server) handleTrackerAddingText(w http.ResponseWriter, r *http.Request) {
+	name, unit := r.PostFormValue("name"), r.PostFormValue("unit")
+	if name == "" {
+		writeError(w, r, apierror.InvalidField("name", "is required"))
+		return
+	}
+
+	if _, err := s.addCatalog(r.Context(), name, unit, nil); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	s.writeTrackerListing(w, r)
+}
+
+// Mark event done, then return plain texts of tracking list.
+func (s *server) handleTrackerMarkingText(w http.ResponseWriter, r *http.Request) {
+	catalogIDText, valueText := r.PostFormValue("catalogID"), r.PostFormValue("value")
+	catalogID, err := strconv.Atoi(catalogIDText)
+	if err != nil {
+		writeError(w, r, apierror.InvalidField("catalogID", "must be an integer"))
+		return
+	}
+
+	var value float64 = 0
+	if valueText != "" {
+		value, err = strconv.ParseFloat(valueText, 64)
+		if err != nil {
+			writeError(w, r, apierror.InvalidField("value", "must be a float"))
+			return
+		}
+	}
+
+	id := auth.FromContext(r.Context())
+	err = s.tracker.MarkDone(r.Context(), id.Username, id.App, catalogID, value)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	s.writeTrackerListing(w, r)
+}
+
+// Return plain texts of whether all catalogs with the `tag` values are done
+// today, e.g. "fitness: 2/3 done, left: 3. Run".
+func (s *server) handleTrackerTodayText(w http.ResponseWriter, r *http.Request) {
+	tags, ok := tagFilter(w, r)
+	if !ok {
+		return
+	}
+	id := auth.FromContext(r.Context())
+	catalogs, err := s.tracker.GetTrackingCatalogs(r.Context(), id.Username, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(tracker.FormatSummary(tracker.Summarize(catalogs, tags...))))
+}
+
+// Return a heatmap of the user's events as Unicode blocks, of the catalog
+// named by `catalogID` if any, else of the catalogs with the `tag` values.
+func (s *server) handleTrackerHeatmapText(w http.ResponseWriter, r *http.Request) {
+	tags, ok := tagFilter(w, r)
+	if !ok {
+		return
+	}
+	id := auth.FromContext(r.Context())
+	catalogs, err := s.tracker.GetTrackingCatalogs(r.Context(), id.Username, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	catalogs = tracker.FilterByTags(catalogs, tags...)
+
+	if text := r.FormValue("catalogID"); text != "" {
+		catalogID, err := strconv.Atoi(text)
+		if err != nil {
+			writeError(w, r, apierror.InvalidField("catalogID", "must be an integer"))
+			return
+		}
+		var found []tracker.Catalog
+		for _, catalog := range catalogs {
+			if catalog.ID == catalogID {
+				found = append(found, catalog)
+			}
+		}
+		if len(found) == 0 {
+			writeError(w, r, tracker.ErrCatalogNotFound)
+			return
+		}
+		catalogs = found
+	}
+
+	s.writeHeatmap(w, r, catalogs, ".txt")
+}
+
+// Write the plain text tracking list of the authenticated user, only with
+// catalogs having the `tag` values if any.
+func (s *server) writeTrackerListing(w http.ResponseWriter, r *http.Request) {
+	tags, ok := tagFilter(w, r)
+	if !ok {
+		return
+	}
+	id := auth.FromContext(r.Context())
+	catalogs, err := s.tracker.GetTrackingCatalogs(r.Context(), id.Username, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if catalogs = tracker.FilterByTags(catalogs, tags...); len(catalogs) == 0 {
+		writeError(w, r, apierror.NotFound("no catalogs for such user"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(tracker.FormatListing(catalogs)))
+}
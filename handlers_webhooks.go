@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/this-is-a-bot/bot/apierror"
+	"github.com/this-is-a-bot/bot/apps"
+	"github.com/this-is-a-bot/bot/auth"
+	"github.com/this-is-a-bot/bot/router"
+	"github.com/this-is-a-bot/bot/webhook"
+)
+
+// Deliveries listed by default, and at most.
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// List active webhooks of an app.
+func (s *server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	id := auth.FromContext(r.Context())
+	webhooks, err := webhook.GetWebhooks(r.Context(), s.db, id.App)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, webhooks)
+}
+
+// Register a webhook described by the JSON body. The answer holds the
+// signing secret, it's not shown again.
+func (s *server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var wh webhook.Webhook
+	if !readJSON(w, r, &wh) {
+		return
+	}
+	wh.App = auth.FromContext(r.Context()).App
+	if err := wh.Validate(); err != nil {
+		writeError(w, r, apierror.Invalid("%v", err))
+		return
+	}
+	max := 0
+	if a := apps.FromContext(r.Context()); a != nil {
+		max = a.MaxWebhooks
+	}
+
+	created, err := webhook.Register(r.Context(), s.db, wh, max)
+	if err == webhook.ErrTooManyWebhooks {
+		writeError(w, r, apierror.QuotaExceeded("app %s allows %d webhooks", wh.App, max))
+		return
+	} else if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", r.URL.Path+"/"+strconv.FormatInt(created.ID, 10))
+	writeJSON(w, r, http.StatusCreated, created)
+}
+
+// Stop delivering to a webhook.
+func (s *server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+	err := webhook.Unregister(r.Context(), s.db, webhookID, auth.FromContext(r.Context()).App)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List the latest deliveries of a webhook, with their status and last error.
+func (s *server) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultDeliveryLimit
+	if text := r.FormValue("limit"); text != "" {
+		var err error
+		limit, err = strconv.Atoi(text)
+		if err != nil || limit <= 0 || limit > maxDeliveryLimit {
+			writeError(w, r, apierror.InvalidField("limit", "must be between 1 and 500"))
+			return
+		}
+	}
+
+	deliveries, err := webhook.GetDeliveries(
+		r.Context(), s.db, webhookID, auth.FromContext(r.Context()).App, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, deliveries)
+}
+
+// Parse the `{id}` path parameter, answering 400 if it's invalid.
+func webhookIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	webhookID, err := strconv.ParseInt(router.Param(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, r, apierror.Invalid("webhook ID must be an integer"))
+		return 0, false
+	}
+	return webhookID, true
+}
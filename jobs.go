@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/this-is-a-bot/bot/cron"
+	"github.com/this-is-a-bot/bot/digest"
+	"github.com/this-is-a-bot/bot/queue"
+	"github.com/this-is-a-bot/bot/steam"
+	"github.com/this-is-a-bot/bot/webhook"
+)
+
+// Run background work until SIGTERM (Heroku dyno restarts) or Ctrl-C, then
+// let running jobs finish.
+func (s *server) runWorker() {
+	ctx, stop := context.WithCancel(context.Background())
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+		sig := <-sigs
+		log.Printf("Received %v, finishing running jobs\n", sig)
+		stop()
+	}()
+
+	log.Printf("Worker running with %d slots\n", s.cfg.WorkerConcurrency)
+	s.work(ctx)
+	log.Println("Worker stopped")
+}
+
+// Run queued jobs and scheduled tasks until ctx is done.
+func (s *server) work(ctx context.Context) {
+	worker := queue.NewWorker(s.jobs, s.cfg.WorkerConcurrency)
+	worker.Handle(webhook.DeliverJob, s.webhooks.Deliver)
+
+	done := make(chan struct{})
+	go func() {
+		s.cron.Run(ctx)
+		close(done)
+	}()
+	worker.Run(ctx)
+	<-done
+}
+
+// Declare the recurring tasks, in the tracker timezone.
+func (s *server) schedule() *cron.Scheduler {
+	loc, _ := time.LoadLocation(s.cfg.Timezone)
+	c := cron.New(s.rs, loc)
+	add := func(name string, spec string, run cron.Func) {
+		// Specs are constants, a bad one is a bug.
+		if err := c.Add(name, spec, run); err != nil {
+			panic(err)
+		}
+	}
+
+	add("steam_cache_warm", "*/5 * * * *", s.warmSteamCache)
+	if s.cfg.Enabled("steam_ingest") {
+		add("steam_ingest", "*/30 * * * *", func(ctx context.Context) error {
+			_, err := steam.NewIngester(s.steam).Ingest(ctx, steam.DefaultCountryCode)
+			return err
+		})
+	}
+	if s.cfg.Enabled("digests") {
+		add("digests", "@hourly", s.deliverDigests)
+	}
+	if s.cfg.Enabled("webhooks") {
+		add("webhook_sweep", "* * * * *", s.webhooks.RequeueOverdue)
+		// Shortly after midnight, once yesterday is over.
+		add("broken_streaks", "5 0 * * *", func(ctx context.Context) error {
+			return s.webhooks.EmitBrokenStreaks(ctx, s.tracker, time.Now())
+		})
+	}
+	return c
+}
+
+// Deliver due digests, to the chat adapter at the `digest_url` secret if
+// set.
+func (s *server) deliverDigests(ctx context.Context) error {
+	var deliverer digest.Deliverer = digest.LogDeliverer{}
+	if url := s.cfg.Secrets["digest_url"]; url != "" {
+		deliverer = &digest.HTTPDeliverer{URL: url}
+	}
+	return digest.RunDue(ctx, s.db, s.steam, deliverer, time.Now())
+}
+
+// Read the default steam listings so the cache is filled before clients
+// ask.
+func (s *server) warmSteamCache(ctx context.Context) error {
+	if _, err := s.steam.GetDiscounts(ctx, steam.DefaultCountryCode); err != nil {
+		return err
+	}
+	for _, feature := range steam.Features {
+		if _, err := s.steam.GetFeatured(ctx, feature, steam.DefaultCountryCode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Header carrying the request ID, accepted from clients and echoed back.
+const RequestIDHeader = "X-Request-ID"
+
+// Client supplied IDs longer than this are replaced.
+const maxRequestIDLength = 128
+
+var (
+	mu     sync.Mutex
+	output io.Writer = os.Stderr
+)
+
+// Set where JSON log lines are written, stderr by default.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	output = w
+	mu.Unlock()
+}
+
+// Fields of one JSON log line.
+type Fields map[string]interface{}
+
+// Write one JSON log line.
+func Log(ctx context.Context, level string, msg string, fields Fields) {
+	line := Fields{
+		"time":  time.Now().UTC().Format(time.RFC3339Nano),
+		"level": level,
+		"msg":   msg,
+	}
+	if info := requestInfo(ctx); info != nil {
+		line["request_id"] = info.id
+	}
+	for k, v := range fields {
+		line[k] = v
+	}
+
+	js, err := json.Marshal(line)
+	if err != nil {
+		js = []byte(fmt.Sprintf(`{"level":"error","msg":%q}`, err.Error()))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	output.Write(append(js, '\n'))
+}
+
+// Log an informational message, tagged with the request ID of ctx.
+func Printf(ctx context.Context, format string, args ...interface{}) {
+	Log(ctx, "info", fmt.Sprintf(format, args...), nil)
+}
+
+// Log an error message, tagged with the request ID of ctx.
+func Errorf(ctx context.Context, format string, args ...interface{}) {
+	Log(ctx, "error", fmt.Sprintf(format, args...), nil)
+}
+
+// Per-request state shared between the middleware and inner handlers.
+type info struct {
+	id string
+
+	mu       sync.Mutex
+	app      string
+	username string
+	path     string
+}
+
+type contextKey struct{}
+
+func requestInfo(ctx context.Context) *info {
+	if ctx == nil {
+		return nil
+	}
+	i, _ := ctx.Value(contextKey{}).(*info)
+	return i
+}
+
+// Get the request ID of ctx, empty if none.
+func RequestID(ctx context.Context) string {
+	if i := requestInfo(ctx); i != nil {
+		return i.id
+	}
+	return ""
+}
+
+// Record who made the request, for the access log.
+func SetUser(ctx context.Context, app string, username string) {
+	if i := requestInfo(ctx); i != nil {
+		i.mu.Lock()
+		i.app, i.username = app, username
+		i.mu.Unlock()
+	}
+}
+
+// Log another path than the requested one, e.g. to keep a secret in the
+// path out of the access log.
+func SetPath(ctx context.Context, path string) {
+	if i := requestInfo(ctx); i != nil {
+		i.mu.Lock()
+		i.path = path
+		i.mu.Unlock()
+	}
+}
+
+// Generate a random request ID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Keeps the status code and size written by a handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Flush passes through so streaming handlers keep working.
+func (rec *responseRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Middleware assigns a request ID (or keeps a valid one sent by the client),
+// echoes it in the response and writes a JSON access log line once the
+// handler is done.
+func Middleware(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		i := &info{id: id}
+		ctx := context.WithValue(r.Context(), contextKey{}, i)
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r.WithContext(ctx))
+
+		i.mu.Lock()
+		fields := Fields{
+			"method":     r.Method,
+			"route":      route,
+			"path":       r.URL.Path,
+			"status":     rec.status,
+			"bytes":      rec.bytes,
+			"latency_ms": float64(time.Since(start)) / float64(time.Millisecond),
+			"remote":     r.RemoteAddr,
+		}
+		if i.path != "" {
+			fields["path"] = i.path
+		}
+		if i.username != "" {
+			fields["username"] = i.username
+			fields["app"] = i.app
+		}
+		i.mu.Unlock()
+
+		level := "info"
+		if rec.status >= 500 {
+			level = "error"
+		}
+		Log(ctx, level, "request", fields)
+	}
+}
@@ -0,0 +1,285 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default latency buckets in seconds.
+var DefaultBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// Registry holds metrics and renders them in the Prometheus text format.
+type Registry struct {
+	mu         sync.Mutex
+	counters   []*CounterVec
+	histograms []*HistogramVec
+	funcs      []*metricFunc
+}
+
+// Registry used by the package level helpers.
+var DefaultRegistry = NewRegistry()
+
+// Create an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// CounterVec is a set of counters partitioned by label values.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// HistogramVec is a set of histograms partitioned by label values.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Metric whose value is read at scrape time, a gauge or a counter.
+type metricFunc struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+// Register a new counter.
+func (reg *Registry) NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name: name, help: help, labels: labels, values: make(map[string]float64),
+	}
+	reg.mu.Lock()
+	reg.counters = append(reg.counters, c)
+	reg.mu.Unlock()
+	return c
+}
+
+// Register a new histogram with the given upper bounds.
+func (reg *Registry) NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		name: name, help: help, labels: labels, buckets: buckets,
+		values: make(map[string]*histogram),
+	}
+	reg.mu.Lock()
+	reg.histograms = append(reg.histograms, h)
+	reg.mu.Unlock()
+	return h
+}
+
+// Register a gauge whose value is read at scrape time.
+func (reg *Registry) NewGaugeFunc(name string, help string, fn func() float64) {
+	reg.mu.Lock()
+	reg.funcs = append(reg.funcs, &metricFunc{name: name, help: help, kind: "gauge", fn: fn})
+	reg.mu.Unlock()
+}
+
+// Register a counter whose value is read at scrape time, for totals kept
+// elsewhere. fn must never decrease.
+func (reg *Registry) NewCounterFunc(name string, help string, fn func() float64) {
+	reg.mu.Lock()
+	reg.funcs = append(reg.funcs, &metricFunc{name: name, help: help, kind: "counter", fn: fn})
+	reg.mu.Unlock()
+}
+
+// Add one to the counter with the given label values.
+func (c *CounterVec) Inc(values ...string) {
+	key := labelKey(c.labels, values)
+	c.mu.Lock()
+	c.values[key]++
+	c.mu.Unlock()
+}
+
+// Record one observation in the histogram with the given label values.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	key := labelKey(h.labels, values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += v
+	hist.count++
+}
+
+// Write all metrics in the Prometheus text exposition format.
+func (reg *Registry) Write(w io.Writer) error {
+	reg.mu.Lock()
+	counters := append([]*CounterVec(nil), reg.counters...)
+	histograms := append([]*HistogramVec(nil), reg.histograms...)
+	funcs := append([]*metricFunc(nil), reg.funcs...)
+	reg.mu.Unlock()
+
+	var b strings.Builder
+	for _, c := range counters {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		c.mu.Lock()
+		for _, key := range sortedKeys(c.values) {
+			fmt.Fprintf(&b, "%s%s %s\n", c.name, braces(key), formatFloat(c.values[key]))
+		}
+		c.mu.Unlock()
+	}
+
+	for _, h := range histograms {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+		h.mu.Lock()
+		keys := make([]string, 0, len(h.values))
+		for key := range h.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			hist := h.values[key]
+			for i, bound := range h.buckets {
+				fmt.Fprintf(
+					&b, "%s_bucket%s %d\n", h.name,
+					braces(joinLabels(key, fmt.Sprintf("le=%q", formatFloat(bound)))),
+					hist.counts[i])
+			}
+			fmt.Fprintf(
+				&b, "%s_bucket%s %d\n", h.name, braces(joinLabels(key, `le="+Inf"`)),
+				hist.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", h.name, braces(key), formatFloat(hist.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", h.name, braces(key), hist.count)
+		}
+		h.mu.Unlock()
+	}
+
+	for _, g := range funcs {
+		fmt.Fprintf(
+			&b, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", g.name, g.help, g.name, g.kind,
+			g.name, formatFloat(g.fn()))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Serve the registry, typically at /metrics.
+func (reg *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		reg.Write(w)
+	}
+}
+
+// Render label values as `name="value",...`, used as map key and output.
+func labelKey(names []string, values []string) string {
+	if len(names) != len(values) {
+		panic(fmt.Sprintf("expected %d label values, got %d", len(names), len(values)))
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%s", name, strconv.Quote(values[i]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(key string, extra string) string {
+	if key == "" {
+		return extra
+	}
+	return key + "," + extra
+}
+
+func braces(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "{" + key + "}"
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	requestsTotal = DefaultRegistry.NewCounterVec(
+		"bot_http_requests_total", "HTTP requests by route, method and status code.",
+		"route", "method", "code")
+	requestErrors = DefaultRegistry.NewCounterVec(
+		"bot_http_request_errors_total", "HTTP requests answered with a 5xx status.",
+		"route", "method")
+	requestDuration = DefaultRegistry.NewHistogramVec(
+		"bot_http_request_duration_seconds", "HTTP request latency.",
+		DefaultBuckets, "route", "method")
+)
+
+// Keeps the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Flush passes through so streaming handlers keep working.
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Instrument wraps a handler to count its requests, errors and latency
+// under the given route name.
+func Instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		code := strconv.Itoa(rec.status)
+		requestsTotal.Inc(route, r.Method, code)
+		if rec.status >= 500 {
+			requestErrors.Inc(route, r.Method)
+		}
+		requestDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+	}
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/this-is-a-bot/bot/redis"
+)
+
+// Export Postgres connection pool stats. Waits are counted since start.
+func (reg *Registry) RegisterDBStats(db *sql.DB) {
+	stats := func(fn func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(db.Stats()) }
+	}
+	reg.NewGaugeFunc(
+		"bot_db_open_connections", "Open Postgres connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	reg.NewGaugeFunc(
+		"bot_db_in_use_connections", "Postgres connections in use.",
+		stats(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	reg.NewGaugeFunc(
+		"bot_db_idle_connections", "Idle Postgres connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	reg.NewCounterFunc(
+		"bot_db_waits_total", "Total waits for a Postgres connection.",
+		stats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	reg.NewCounterFunc(
+		"bot_db_wait_duration_seconds_total", "Total time waited for a Postgres connection.",
+		stats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+}
+
+// Export Redis connection pool stats as gauges.
+func (reg *Registry) RegisterRedisStats(rs redis.RedisStore) {
+	reg.NewGaugeFunc(
+		"bot_redis_active_connections", "Active Redis connections, idle included.",
+		func() float64 { return float64(rs.Stats().ActiveCount) })
+	reg.NewGaugeFunc(
+		"bot_redis_idle_connections", "Idle Redis connections.",
+		func() float64 { return float64(rs.Stats().IdleCount) })
+}
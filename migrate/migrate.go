@@ -0,0 +1,191 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const schemaMigrationsTableName = "schema_migrations"
+
+// Key of the Postgres advisory lock serializing migrations across dynos.
+const lockID = 7301984
+
+var (
+	createSchemaMigrations string
+	queryAppliedVersions   string
+	queryAppliedAt         string
+	insertVersion          string
+	deleteVersion          string
+)
+
+// Prepare queries.
+func init() {
+	createSchemaMigrations = fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s ("+
+			"version integer PRIMARY KEY, name text NOT NULL, "+
+			"applied_at timestamptz NOT NULL DEFAULT now())",
+		schemaMigrationsTableName)
+
+	queryAppliedVersions = fmt.Sprintf(
+		"SELECT version FROM %s ORDER BY version", schemaMigrationsTableName)
+
+	queryAppliedAt = fmt.Sprintf(
+		"SELECT version, applied_at FROM %s", schemaMigrationsTableName)
+
+	insertVersion = fmt.Sprintf(
+		"INSERT INTO %s (version, name) VALUES ($1, $2)", schemaMigrationsTableName)
+
+	deleteVersion = fmt.Sprintf(
+		"DELETE FROM %s WHERE version = $1", schemaMigrationsTableName)
+}
+
+// One versioned schema change with its reverse.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Whether a migration has been applied, and when.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Apply all pending migrations in order, returning the applied ones.
+func Up(db *sql.DB) ([]Migration, error) {
+	applied := make([]Migration, 0)
+	for {
+		m, err := step(db, func(versions map[int]bool) *Migration {
+			for i := range migrations {
+				if !versions[migrations[i].Version] {
+					return &migrations[i]
+				}
+			}
+			return nil
+		}, true)
+		if err != nil || m == nil {
+			return applied, err
+		}
+		applied = append(applied, *m)
+	}
+}
+
+// Revert the latest n applied migrations, returning the reverted ones.
+func Down(db *sql.DB, n int) ([]Migration, error) {
+	reverted := make([]Migration, 0)
+	for i := 0; i < n; i++ {
+		m, err := step(db, func(versions map[int]bool) *Migration {
+			for j := len(migrations) - 1; j >= 0; j-- {
+				if versions[migrations[j].Version] {
+					return &migrations[j]
+				}
+			}
+			return nil
+		}, false)
+		if err != nil || m == nil {
+			return reverted, err
+		}
+		reverted = append(reverted, *m)
+	}
+	return reverted, nil
+}
+
+// List all known migrations and whether they have been applied.
+func GetStatus(db *sql.DB) ([]Status, error) {
+	if _, err := db.Exec(createSchemaMigrations); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(queryAppliedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	res := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		status := Status{Migration: m}
+		if at, ok := appliedAt[m.Version]; ok {
+			status.AppliedAt = &at
+		}
+		res = append(res, status)
+	}
+	return res, nil
+}
+
+// Run one migration in its own transaction while holding the advisory lock.
+// The applied versions are read under the lock, so a dyno that waited for
+// another one sees its work and doesn't apply anything twice.
+func step(db *sql.DB, pick func(versions map[int]bool) *Migration, up bool) (*Migration, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("SELECT pg_advisory_xact_lock($1)", lockID); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(createSchemaMigrations); err != nil {
+		return nil, err
+	}
+
+	versions, err := appliedVersions(tx)
+	if err != nil {
+		return nil, err
+	}
+	m := pick(versions)
+	if m == nil {
+		return nil, tx.Commit()
+	}
+
+	if up {
+		if _, err = tx.Exec(m.Up); err != nil {
+			return nil, fmt.Errorf("migration %d (%s): %v", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec(insertVersion, m.Version, m.Name)
+	} else {
+		if _, err = tx.Exec(m.Down); err != nil {
+			return nil, fmt.Errorf("migration %d (%s) down: %v", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec(deleteVersion, m.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, tx.Commit()
+}
+
+func appliedVersions(tx *sql.Tx) (map[int]bool, error) {
+	rows, err := tx.Query(queryAppliedVersions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err = rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		res[version] = true
+	}
+	return res, rows.Err()
+}
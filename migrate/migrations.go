@@ -0,0 +1,301 @@
+package migrate
+
+// All migrations, in order. Never edit an applied migration, add a new one.
+//
+// The first one uses IF NOT EXISTS since the tables predate the migrations
+// and already exist in production.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_tracker_and_steam",
+		Up: `
+CREATE TABLE IF NOT EXISTS tracker_catalog (
+	id serial PRIMARY KEY,
+	username text NOT NULL,
+	app text NOT NULL,
+	name text NOT NULL,
+	unit text NOT NULL DEFAULT '',
+	latest_event integer,
+	disabled boolean NOT NULL DEFAULT false,
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS tracker_catalog_username_app_idx
+	ON tracker_catalog (username, app);
+
+CREATE TABLE IF NOT EXISTS tracker_events (
+	id serial PRIMARY KEY,
+	catalog_id integer NOT NULL REFERENCES tracker_catalog (id) ON DELETE CASCADE,
+	value real NOT NULL DEFAULT 0,
+	marked_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS tracker_events_catalog_id_marked_at_idx
+	ON tracker_events (catalog_id, marked_at);
+
+CREATE TABLE IF NOT EXISTS steam_discount_game (
+	name text NOT NULL,
+	link text NOT NULL,
+	img_src text NOT NULL DEFAULT '',
+	review text NOT NULL DEFAULT '',
+	price_before real NOT NULL DEFAULT 0,
+	price_now real NOT NULL DEFAULT 0,
+	discount text NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS steam_featured_game (
+	feature_type text NOT NULL,
+	name text NOT NULL,
+	link text NOT NULL,
+	img_src text NOT NULL DEFAULT '',
+	headline text NOT NULL DEFAULT '',
+	price_before real NOT NULL DEFAULT 0,
+	price_now real NOT NULL DEFAULT 0,
+	discount text NOT NULL DEFAULT ''
+);
+`,
+		Down: `
+DROP TABLE steam_featured_game;
+DROP TABLE steam_discount_game;
+DROP TABLE tracker_events;
+DROP TABLE tracker_catalog;
+`,
+	},
+	{
+		Version: 2,
+		Name:    "steam_regions",
+		Up: `
+ALTER TABLE steam_discount_game
+	ADD COLUMN IF NOT EXISTS country_code text NOT NULL DEFAULT 'us',
+	ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'USD';
+CREATE INDEX steam_discount_game_country_code_idx
+	ON steam_discount_game (country_code);
+
+ALTER TABLE steam_featured_game
+	ADD COLUMN IF NOT EXISTS country_code text NOT NULL DEFAULT 'us',
+	ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'USD';
+CREATE INDEX steam_featured_game_country_code_feature_type_idx
+	ON steam_featured_game (country_code, feature_type);
+
+CREATE TABLE steam_exchange_rate (
+	currency text PRIMARY KEY,
+	rate double precision NOT NULL CHECK (rate > 0),
+	updated_at timestamptz NOT NULL DEFAULT now()
+);
+INSERT INTO steam_exchange_rate (currency, rate) VALUES ('USD', 1);
+`,
+		Down: `
+DROP TABLE steam_exchange_rate;
+DROP INDEX steam_featured_game_country_code_feature_type_idx;
+ALTER TABLE steam_featured_game DROP COLUMN country_code, DROP COLUMN currency;
+DROP INDEX steam_discount_game_country_code_idx;
+ALTER TABLE steam_discount_game DROP COLUMN country_code, DROP COLUMN currency;
+`,
+	},
+	{
+		Version: 3,
+		Name:    "steam_app_ids_and_price_history",
+		Up: `
+ALTER TABLE steam_discount_game ADD COLUMN IF NOT EXISTS app_id integer;
+ALTER TABLE steam_featured_game ADD COLUMN IF NOT EXISTS app_id integer;
+UPDATE steam_discount_game
+	SET app_id = substring(link from '/app/([0-9]+)')::integer
+	WHERE app_id IS NULL AND link ~ '/app/[0-9]+';
+UPDATE steam_featured_game
+	SET app_id = substring(link from '/app/([0-9]+)')::integer
+	WHERE app_id IS NULL AND link ~ '/app/[0-9]+';
+CREATE INDEX steam_discount_game_app_id_idx ON steam_discount_game (app_id);
+CREATE INDEX steam_featured_game_app_id_idx ON steam_featured_game (app_id);
+
+CREATE TABLE steam_price_history (
+	id bigserial PRIMARY KEY,
+	app_id integer NOT NULL,
+	country_code text NOT NULL,
+	currency text NOT NULL,
+	price_before real NOT NULL,
+	price_now real NOT NULL,
+	discount text NOT NULL DEFAULT '',
+	recorded_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX steam_price_history_app_id_idx
+	ON steam_price_history (app_id, country_code, recorded_at);
+`,
+		Down: `
+DROP TABLE steam_price_history;
+DROP INDEX steam_featured_game_app_id_idx;
+DROP INDEX steam_discount_game_app_id_idx;
+ALTER TABLE steam_featured_game DROP COLUMN app_id;
+ALTER TABLE steam_discount_game DROP COLUMN app_id;
+`,
+	},
+	{
+		Version: 4,
+		Name:    "steam_digests",
+		Up: `
+CREATE TABLE steam_digest_subscription (
+	id bigserial PRIMARY KEY,
+	username text NOT NULL,
+	app text NOT NULL,
+	channel text NOT NULL DEFAULT '',
+	frequency text NOT NULL CHECK (frequency IN ('daily', 'weekly')),
+	platform text NOT NULL DEFAULT '',
+	country_code text NOT NULL DEFAULT 'us',
+	max_price real NOT NULL DEFAULT 0,
+	min_review integer NOT NULL DEFAULT 0,
+	format text NOT NULL DEFAULT 'text',
+	last_sent_at timestamptz,
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX steam_digest_subscription_username_app_idx
+	ON steam_digest_subscription (username, app);
+
+CREATE TABLE steam_digest_sent (
+	subscription_id bigint NOT NULL
+		REFERENCES steam_digest_subscription (id) ON DELETE CASCADE,
+	app_id integer NOT NULL,
+	sent_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX steam_digest_sent_subscription_id_idx
+	ON steam_digest_sent (subscription_id, sent_at);
+`,
+		Down: `
+DROP TABLE steam_digest_sent;
+DROP TABLE steam_digest_subscription;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "api_keys",
+		Up: `
+CREATE TABLE api_key (
+	id bigserial PRIMARY KEY,
+	app text NOT NULL,
+	username text NOT NULL DEFAULT '',
+	name text NOT NULL DEFAULT '',
+	key_hash text NOT NULL UNIQUE,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	revoked_at timestamptz
+);
+`,
+		Down: `
+DROP TABLE api_key;
+`,
+	},
+	{
+		Version: 6,
+		Name:    "webhooks",
+		Up: `
+CREATE TABLE webhook (
+	id bigserial PRIMARY KEY,
+	app text NOT NULL,
+	url text NOT NULL,
+	secret text NOT NULL,
+	events text NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	disabled boolean NOT NULL DEFAULT FALSE
+);
+CREATE INDEX webhook_app_idx ON webhook (app);
+
+CREATE TABLE webhook_delivery (
+	id bigserial PRIMARY KEY,
+	webhook_id bigint NOT NULL REFERENCES webhook (id) ON DELETE CASCADE,
+	event text NOT NULL,
+	payload text NOT NULL,
+	status text NOT NULL DEFAULT 'pending'
+		CHECK (status IN ('pending', 'delivered', 'failed')),
+	attempts integer NOT NULL DEFAULT 0,
+	response_status integer NOT NULL DEFAULT 0,
+	last_error text NOT NULL DEFAULT '',
+	next_attempt_at timestamptz NOT NULL DEFAULT now(),
+	created_at timestamptz NOT NULL DEFAULT now(),
+	delivered_at timestamptz
+);
+CREATE INDEX webhook_delivery_webhook_id_idx
+	ON webhook_delivery (webhook_id, created_at);
+CREATE INDEX webhook_delivery_pending_idx
+	ON webhook_delivery (next_attempt_at) WHERE status = 'pending';
+`,
+		Down: `
+DROP TABLE webhook_delivery;
+DROP TABLE webhook;
+`,
+	},
+	{
+		Version: 7,
+		Name:    "apps",
+		Up: `
+CREATE TABLE app (
+	name text PRIMARY KEY,
+	timezone text NOT NULL DEFAULT '',
+	digest_frequency text NOT NULL DEFAULT 'daily'
+		CHECK (digest_frequency IN ('daily', 'weekly')),
+	max_catalogs_per_user integer NOT NULL DEFAULT 0 CHECK (max_catalogs_per_user >= 0),
+	max_webhooks integer NOT NULL DEFAULT 0 CHECK (max_webhooks >= 0),
+	created_at timestamptz NOT NULL DEFAULT now(),
+	disabled_at timestamptz
+);
+
+-- Register every app already in use.
+INSERT INTO app (name)
+	SELECT app FROM tracker_catalog
+	UNION SELECT app FROM api_key
+	UNION SELECT app FROM steam_digest_subscription
+	UNION SELECT app FROM webhook;
+
+ALTER TABLE tracker_catalog ADD CONSTRAINT tracker_catalog_app_fkey
+	FOREIGN KEY (app) REFERENCES app (name) ON UPDATE CASCADE;
+ALTER TABLE api_key ADD CONSTRAINT api_key_app_fkey
+	FOREIGN KEY (app) REFERENCES app (name) ON UPDATE CASCADE;
+ALTER TABLE steam_digest_subscription ADD CONSTRAINT steam_digest_subscription_app_fkey
+	FOREIGN KEY (app) REFERENCES app (name) ON UPDATE CASCADE;
+ALTER TABLE webhook ADD CONSTRAINT webhook_app_fkey
+	FOREIGN KEY (app) REFERENCES app (name) ON UPDATE CASCADE;
+`,
+		Down: `
+ALTER TABLE webhook DROP CONSTRAINT webhook_app_fkey;
+ALTER TABLE steam_digest_subscription DROP CONSTRAINT steam_digest_subscription_app_fkey;
+ALTER TABLE api_key DROP CONSTRAINT api_key_app_fkey;
+ALTER TABLE tracker_catalog DROP CONSTRAINT tracker_catalog_app_fkey;
+DROP TABLE app;
+`,
+	},
+	{
+		Version: 8,
+		Name:    "catalog_tags",
+		Up: `
+CREATE TABLE tracker_catalog_tag (
+	catalog_id integer NOT NULL REFERENCES tracker_catalog (id) ON DELETE CASCADE,
+	tag text NOT NULL,
+	PRIMARY KEY (catalog_id, tag)
+);
+CREATE INDEX tracker_catalog_tag_tag_idx ON tracker_catalog_tag (tag);
+`,
+		Down: `
+DROP TABLE tracker_catalog_tag;
+`,
+	},
+	{
+		Version: 9,
+		Name:    "calendar_feeds",
+		Up: `
+CREATE TABLE calendar_feed (
+	app text NOT NULL REFERENCES app (name) ON UPDATE CASCADE,
+	username text NOT NULL,
+	token_hash text NOT NULL UNIQUE,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (app, username)
+);
+`,
+		Down: `
+DROP TABLE calendar_feed;
+`,
+	},
+	{
+		Version: 10,
+		Name:    "webhook_delivery_jobs",
+		Up: `
+ALTER TABLE webhook_delivery ADD COLUMN job_id text NOT NULL DEFAULT '';
+`,
+		Down: `
+ALTER TABLE webhook_delivery DROP COLUMN job_id;
+`,
+	},
+}
@@ -0,0 +1,13 @@
+package openapi
+
+import (
+	"net/http"
+)
+
+// Serve the specification as JSON.
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(Spec))
+	}
+}
@@ -0,0 +1,3952 @@
+package openapi
+
+// Spec is the OpenAPI 3 document of the bot API. Update it along with the
+// routes, then run `go generate ./client`.
+const Spec = `{
+  "openapi": "3.0.2",
+  "info": {
+    "title": "bot",
+    "version": "1.0.0",
+    "description": "Steam deals and habit tracking for chat bots. Errors are a JSON envelope if the client accepts application/json, plain text otherwise."
+  },
+  "servers": [
+    {
+      "url": "/"
+    }
+  ],
+  "tags": [
+    {
+      "name": "meta"
+    },
+    {
+      "name": "steam"
+    },
+    {
+      "name": "digests"
+    },
+    {
+      "name": "tracker"
+    },
+    {
+      "name": "apps"
+    },
+    {
+      "name": "webhooks"
+    },
+    {
+      "name": "admin",
+      "description": "Operator endpoints, authenticated with the admin token."
+    },
+    {
+      "name": "legacy",
+      "description": "Unversioned routes kept for existing chat adapters."
+    }
+  ],
+  "paths": {
+    "/": {
+      "get": {
+        "operationId": "getIndex",
+        "summary": "Greeting.",
+        "tags": [
+          "meta"
+        ],
+        "responses": {
+          "200": {
+            "description": "Welcome message.",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/healthz": {
+      "get": {
+        "operationId": "getHealth",
+        "summary": "Liveness, always 200 with the state of each dependency.",
+        "tags": [
+          "meta"
+        ],
+        "responses": {
+          "200": {
+            "description": "Dependency checks.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Checks"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/readyz": {
+      "get": {
+        "operationId": "getReadiness",
+        "summary": "Readiness, 503 if a dependency is down or the server is shutting down.",
+        "tags": [
+          "meta"
+        ],
+        "responses": {
+          "200": {
+            "description": "Ready.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Checks"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "Not ready.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Checks"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metrics": {
+      "get": {
+        "operationId": "getMetrics",
+        "summary": "Prometheus metrics.",
+        "tags": [
+          "meta"
+        ],
+        "responses": {
+          "200": {
+            "description": "Metrics in the Prometheus text format.",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "adminToken": []
+          }
+        ]
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "operationId": "getOpenAPI",
+        "summary": "This document.",
+        "tags": [
+          "meta"
+        ],
+        "responses": {
+          "200": {
+            "description": "OpenAPI 3 document.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v1/admin/cron": {
+      "get": {
+        "operationId": "listCronTasks",
+        "summary": "List scheduled tasks and their last run.",
+        "tags": [
+          "admin"
+        ],
+        "responses": {
+          "200": {
+            "description": "Tasks declared by the deployment.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/CronTask"
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "adminToken": []
+          }
+        ]
+      }
+    },
+    "/v1/admin/apps": {
+      "get": {
+        "operationId": "listApps",
+        "summary": "List registered apps.",
+        "tags": [
+          "admin"
+        ],
+        "responses": {
+          "200": {
+            "description": "Apps, disabled ones included.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/App"
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "adminToken": []
+          }
+        ]
+      },
+      "post": {
+        "operationId": "createApp",
+        "summary": "Register an app.",
+        "tags": [
+          "admin"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/AppRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The new app.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/App"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "409": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "adminToken": []
+          }
+        ]
+      }
+    },
+    "/v1/admin/apps/{app}": {
+      "parameters": [
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getApp",
+        "summary": "Get one app.",
+        "tags": [
+          "admin"
+        ],
+        "responses": {
+          "200": {
+            "description": "The app.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/App"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "adminToken": []
+          }
+        ]
+      },
+      "patch": {
+        "operationId": "updateApp",
+        "summary": "Change the settings or quotas of an app, or disable it.",
+        "tags": [
+          "admin"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/AppRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated app.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/App"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "adminToken": []
+          }
+        ]
+      }
+    },
+    "/v1/steam/discounts": {
+      "get": {
+        "operationId": "listSteamDiscounts",
+        "summary": "List discounted games.",
+        "tags": [
+          "steam"
+        ],
+        "parameters": [
+          {
+            "name": "cc",
+            "in": "query",
+            "description": "Two-letter country code of the store region, us by default.",
+            "schema": {
+              "type": "string",
+              "pattern": "^[a-zA-Z]{2}$"
+            }
+          },
+          {
+            "name": "currency",
+            "in": "query",
+            "description": "Convert prices to this ISO 4217 currency, e.g. EUR.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Games, most recent crawl first.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/SteamGame"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/v1/steam/featured": {
+      "get": {
+        "operationId": "listSteamFeatured",
+        "summary": "List featured games of a category.",
+        "tags": [
+          "steam"
+        ],
+        "parameters": [
+          {
+            "name": "feature",
+            "in": "query",
+            "description": "Featured category, win by default.",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "win",
+                "linux",
+                "mac",
+                "top_sellers",
+                "new_releases",
+                "coming_soon",
+                "vr",
+                "free_to_play"
+              ]
+            }
+          },
+          {
+            "name": "cc",
+            "in": "query",
+            "description": "Two-letter country code of the store region, us by default.",
+            "schema": {
+              "type": "string",
+              "pattern": "^[a-zA-Z]{2}$"
+            }
+          },
+          {
+            "name": "currency",
+            "in": "query",
+            "description": "Convert prices to this ISO 4217 currency, e.g. EUR.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Games, most recent crawl first.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/SteamGame"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/v1/steam/games/{appid}": {
+      "get": {
+        "operationId": "getSteamGame",
+        "summary": "Get details and price history of a game.",
+        "tags": [
+          "steam"
+        ],
+        "parameters": [
+          {
+            "name": "appid",
+            "in": "path",
+            "description": "Steam app ID.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1
+            },
+            "required": true
+          },
+          {
+            "name": "cc",
+            "in": "query",
+            "description": "Two-letter country code of the store region, us by default.",
+            "schema": {
+              "type": "string",
+              "pattern": "^[a-zA-Z]{2}$"
+            }
+          },
+          {
+            "name": "currency",
+            "in": "query",
+            "description": "Convert prices to this ISO 4217 currency, e.g. EUR.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The game.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/GameDetail"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/v1/apps/{app}": {
+      "parameters": [
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getOwnApp",
+        "summary": "Get the settings and quotas of an app.",
+        "description": "Requires an app key.",
+        "tags": [
+          "apps"
+        ],
+        "responses": {
+          "200": {
+            "description": "The app.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/App"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/users/{user}/apps/{app}/digests": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "listDigests",
+        "summary": "List digest subscriptions of a user.",
+        "tags": [
+          "digests"
+        ],
+        "responses": {
+          "200": {
+            "description": "Subscriptions.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Subscription"
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      },
+      "post": {
+        "operationId": "createDigest",
+        "summary": "Subscribe a user to a digest.",
+        "tags": [
+          "digests"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Subscription"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The new subscription.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Subscription"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/users/{user}/apps/{app}/digests/{id}": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "id",
+          "in": "path",
+          "description": "Subscription ID.",
+          "schema": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "required": true
+        }
+      ],
+      "delete": {
+        "operationId": "deleteDigest",
+        "summary": "Unsubscribe a user from a digest.",
+        "tags": [
+          "digests"
+        ],
+        "responses": {
+          "204": {
+            "description": "Deleted."
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/apps/{app}/webhooks": {
+      "parameters": [
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "listWebhooks",
+        "summary": "List webhooks of an app.",
+        "description": "Requires an app key.",
+        "tags": [
+          "webhooks"
+        ],
+        "responses": {
+          "200": {
+            "description": "Webhooks, without secrets.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Webhook"
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      },
+      "post": {
+        "operationId": "createWebhook",
+        "summary": "Register a webhook.",
+        "description": "Requires an app key. Deliveries are posted as a WebhookPayload, signed in the X-Bot-Signature header as t=<unix time>,v1=<hex HMAC-SHA256 of <unix time>.<body> keyed with the secret>. Failed deliveries are retried with exponential backoff, up to 10 attempts. Answers 403 with quota_exceeded once the app has as many webhooks as it allows.",
+        "tags": [
+          "webhooks"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Webhook"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The new webhook, with its secret.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Webhook"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/apps/{app}/webhooks/{id}": {
+      "parameters": [
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "id",
+          "in": "path",
+          "description": "Webhook ID.",
+          "schema": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "required": true
+        }
+      ],
+      "delete": {
+        "operationId": "deleteWebhook",
+        "summary": "Stop delivering to a webhook.",
+        "tags": [
+          "webhooks"
+        ],
+        "responses": {
+          "204": {
+            "description": "Deleted."
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/apps/{app}/webhooks/{id}/deliveries": {
+      "parameters": [
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "id",
+          "in": "path",
+          "description": "Webhook ID.",
+          "schema": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "listWebhookDeliveries",
+        "summary": "List the latest deliveries of a webhook.",
+        "tags": [
+          "webhooks"
+        ],
+        "parameters": [
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Deliveries to list, 50 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 500
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Deliveries, most recent first.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Delivery"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/users/{user}/apps/{app}/catalogs": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "listCatalogs",
+        "summary": "List tracking catalogs of a user with todays status.",
+        "tags": [
+          "tracker"
+        ],
+        "responses": {
+          "200": {
+            "description": "Catalogs ordered by ID.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Catalog"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only keep catalogs having this tag, repeat to require several.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          }
+        ]
+      },
+      "post": {
+        "operationId": "createCatalog",
+        "summary": "Add a tracking catalog.",
+        "tags": [
+          "tracker"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CatalogRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The new catalog.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Catalog"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "description": "Answers 403 with quota_exceeded once the user has as many catalogs as the app allows."
+      }
+    },
+    "/v1/users/{user}/apps/{app}/catalogs/{id}": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "id",
+          "in": "path",
+          "description": "Catalog ID.",
+          "schema": {
+            "type": "integer"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getCatalog",
+        "summary": "Get a tracking catalog.",
+        "tags": [
+          "tracker"
+        ],
+        "responses": {
+          "200": {
+            "description": "The catalog.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Catalog"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      },
+      "patch": {
+        "operationId": "updateCatalog",
+        "summary": "Rename a catalog or change its unit.",
+        "tags": [
+          "tracker"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CatalogRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated catalog.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Catalog"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      },
+      "delete": {
+        "operationId": "deleteCatalog",
+        "summary": "Remove a catalog, its history is kept.",
+        "tags": [
+          "tracker"
+        ],
+        "responses": {
+          "204": {
+            "description": "Deleted."
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/users/{user}/apps/{app}/catalogs/{id}/events": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "id",
+          "in": "path",
+          "description": "Catalog ID.",
+          "schema": {
+            "type": "integer"
+          },
+          "required": true
+        }
+      ],
+      "post": {
+        "operationId": "createEvent",
+        "summary": "Mark a catalog done for today.",
+        "tags": [
+          "tracker"
+        ],
+        "requestBody": {
+          "required": false,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/EventRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The catalog with its new status.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Catalog"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/users/{user}/apps/{app}/catalogs/{id}/heatmap": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "id",
+          "in": "path",
+          "description": "Catalog ID.",
+          "schema": {
+            "type": "integer"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getCatalogHeatmap",
+        "summary": "Get a heatmap of the days a catalog was marked.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "weeks",
+            "in": "query",
+            "description": "Weeks covered up to this one, 53 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 53
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The heatmap.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Heatmap"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/users/{user}/apps/{app}/catalogs/{id}/heatmap.svg": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "id",
+          "in": "path",
+          "description": "Catalog ID.",
+          "schema": {
+            "type": "integer"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getCatalogHeatmapSvg",
+        "summary": "Get a heatmap of the days a catalog was marked as an SVG image with month and weekday labels.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "weeks",
+            "in": "query",
+            "description": "Weeks covered up to this one, 53 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 53
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The heatmap as an SVG image with month and weekday labels.",
+            "content": {
+              "image/svg+xml": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/users/{user}/apps/{app}/catalogs/{id}/heatmap.png": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "id",
+          "in": "path",
+          "description": "Catalog ID.",
+          "schema": {
+            "type": "integer"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getCatalogHeatmapPng",
+        "summary": "Get a heatmap of the days a catalog was marked as a PNG image of the cells alone.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "weeks",
+            "in": "query",
+            "description": "Weeks covered up to this one, 53 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 53
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The heatmap as a PNG image of the cells alone.",
+            "content": {
+              "image/png": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/users/{user}/apps/{app}/catalogs/{id}/heatmap.txt": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "id",
+          "in": "path",
+          "description": "Catalog ID.",
+          "schema": {
+            "type": "integer"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getCatalogHeatmapText",
+        "summary": "Get a heatmap of the days a catalog was marked as Unicode blocks for chat.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "weeks",
+            "in": "query",
+            "description": "Weeks covered up to this one, 53 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 53
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The heatmap as Unicode blocks for chat, a summary line then one line per weekday from Sunday.",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/users/{user}/apps/{app}/today": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getTrackerToday",
+        "summary": "Whether all catalogs having the tags are done today.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only keep catalogs having this tag, repeat to require several.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Today's progress.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TrackerSummary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "description": "E.g. tag=fitness answers whether all the user's fitness habits are done. Without tags, covers every catalog."
+      }
+    },
+    "/v1/users/{user}/apps/{app}/heatmap": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getUserHeatmap",
+        "summary": "Get a heatmap of the days a user marked catalogs.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only keep catalogs having this tag, repeat to require several.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          },
+          {
+            "name": "weeks",
+            "in": "query",
+            "description": "Weeks covered up to this one, 53 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 53
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The heatmap.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Heatmap"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "description": "Counts events of every active catalog, or of those having the tags."
+      }
+    },
+    "/v1/users/{user}/apps/{app}/heatmap.svg": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getUserHeatmapSvg",
+        "summary": "Get a heatmap of the days a user marked catalogs as an SVG image with month and weekday labels.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only keep catalogs having this tag, repeat to require several.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          },
+          {
+            "name": "weeks",
+            "in": "query",
+            "description": "Weeks covered up to this one, 53 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 53
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The heatmap as an SVG image with month and weekday labels.",
+            "content": {
+              "image/svg+xml": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "description": "Counts events of every active catalog, or of those having the tags."
+      }
+    },
+    "/v1/users/{user}/apps/{app}/heatmap.png": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getUserHeatmapPng",
+        "summary": "Get a heatmap of the days a user marked catalogs as a PNG image of the cells alone.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only keep catalogs having this tag, repeat to require several.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          },
+          {
+            "name": "weeks",
+            "in": "query",
+            "description": "Weeks covered up to this one, 53 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 53
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The heatmap as a PNG image of the cells alone.",
+            "content": {
+              "image/png": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "description": "Counts events of every active catalog, or of those having the tags."
+      }
+    },
+    "/v1/users/{user}/apps/{app}/heatmap.txt": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getUserHeatmapText",
+        "summary": "Get a heatmap of the days a user marked catalogs as Unicode blocks for chat.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only keep catalogs having this tag, repeat to require several.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          },
+          {
+            "name": "weeks",
+            "in": "query",
+            "description": "Weeks covered up to this one, 53 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 53
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The heatmap as Unicode blocks for chat, a summary line then one line per weekday from Sunday.",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "description": "Counts events of every active catalog, or of those having the tags."
+      }
+    },
+    "/v1/users/{user}/apps/{app}/calendar": {
+      "parameters": [
+        {
+          "name": "user",
+          "in": "path",
+          "description": "Username of the user in the app.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        },
+        {
+          "name": "app",
+          "in": "path",
+          "description": "Name of the app, e.g. slack.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "post": {
+        "operationId": "createCalendarFeed",
+        "summary": "Issue the token of the user's calendar feed.",
+        "tags": [
+          "tracker"
+        ],
+        "description": "Replaces any previous token, so subscriptions with the old URL stop working.",
+        "responses": {
+          "201": {
+            "description": "The feed.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CalendarFeed"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      },
+      "delete": {
+        "operationId": "deleteCalendarFeed",
+        "summary": "Revoke the user's calendar feed.",
+        "tags": [
+          "tracker"
+        ],
+        "responses": {
+          "204": {
+            "description": "Revoked."
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    },
+    "/v1/calendars/{token}/habits.ics": {
+      "parameters": [
+        {
+          "name": "token",
+          "in": "path",
+          "description": "Feed token.",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "operationId": "getCalendarFeed",
+        "summary": "Get a user's habits as iCalendar.",
+        "tags": [
+          "tracker"
+        ],
+        "description": "The token is the credential since calendar apps can't send API keys. Days catalogs were done in the last year are all-day events, and each catalog is a to-do recurring daily from its next undone day.",
+        "responses": {
+          "200": {
+            "description": "The calendar.",
+            "content": {
+              "text/calendar": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/steam/discounts": {
+      "get": {
+        "operationId": "legacyListSteamDiscounts",
+        "summary": "List discounted games.",
+        "tags": [
+          "legacy"
+        ],
+        "parameters": [
+          {
+            "name": "cc",
+            "in": "query",
+            "description": "Two-letter country code of the store region, us by default.",
+            "schema": {
+              "type": "string",
+              "pattern": "^[a-zA-Z]{2}$"
+            }
+          },
+          {
+            "name": "currency",
+            "in": "query",
+            "description": "Convert prices to this ISO 4217 currency, e.g. EUR.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Games, most recent crawl first.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/SteamGame"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "deprecated": true
+      }
+    },
+    "/steam/featured": {
+      "get": {
+        "operationId": "legacyListSteamFeatured",
+        "summary": "List featured games of a category.",
+        "tags": [
+          "legacy"
+        ],
+        "parameters": [
+          {
+            "name": "feature",
+            "in": "query",
+            "description": "Featured category, win by default.",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "win",
+                "linux",
+                "mac",
+                "top_sellers",
+                "new_releases",
+                "coming_soon",
+                "vr",
+                "free_to_play"
+              ]
+            }
+          },
+          {
+            "name": "cc",
+            "in": "query",
+            "description": "Two-letter country code of the store region, us by default.",
+            "schema": {
+              "type": "string",
+              "pattern": "^[a-zA-Z]{2}$"
+            }
+          },
+          {
+            "name": "currency",
+            "in": "query",
+            "description": "Convert prices to this ISO 4217 currency, e.g. EUR.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Games, most recent crawl first.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/SteamGame"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "deprecated": true
+      }
+    },
+    "/steam/game/{appid}": {
+      "get": {
+        "operationId": "legacyGetSteamGame",
+        "summary": "Get details and price history of a game.",
+        "tags": [
+          "legacy"
+        ],
+        "parameters": [
+          {
+            "name": "appid",
+            "in": "path",
+            "description": "Steam app ID.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1
+            },
+            "required": true
+          },
+          {
+            "name": "cc",
+            "in": "query",
+            "description": "Two-letter country code of the store region, us by default.",
+            "schema": {
+              "type": "string",
+              "pattern": "^[a-zA-Z]{2}$"
+            }
+          },
+          {
+            "name": "currency",
+            "in": "query",
+            "description": "Convert prices to this ISO 4217 currency, e.g. EUR.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The game.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/GameDetail"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "deprecated": true
+      }
+    },
+    "/steam/digests": {
+      "get": {
+        "operationId": "legacyListDigests",
+        "summary": "List digest subscriptions.",
+        "tags": [
+          "legacy"
+        ],
+        "parameters": [
+          {
+            "name": "username",
+            "in": "query",
+            "description": "User to act as, required for app keys.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Subscriptions.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Subscription"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "deprecated": true
+      },
+      "post": {
+        "operationId": "legacyCreateDigest",
+        "summary": "Subscribe, then list subscriptions.",
+        "tags": [
+          "legacy"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "username": {
+                    "type": "string"
+                  },
+                  "channel": {
+                    "type": "string"
+                  },
+                  "frequency": {
+                    "type": "string",
+                    "enum": [
+                      "daily",
+                      "weekly"
+                    ]
+                  },
+                  "platform": {
+                    "type": "string",
+                    "enum": [
+                      "win",
+                      "mac",
+                      "linux"
+                    ]
+                  },
+                  "cc": {
+                    "type": "string"
+                  },
+                  "maxPrice": {
+                    "type": "number"
+                  },
+                  "minReview": {
+                    "type": "integer"
+                  },
+                  "format": {
+                    "type": "string",
+                    "enum": [
+                      "text",
+                      "cards"
+                    ]
+                  }
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Subscriptions.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Subscription"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "deprecated": true
+      },
+      "delete": {
+        "operationId": "legacyDeleteDigest",
+        "summary": "Unsubscribe, then list subscriptions.",
+        "tags": [
+          "legacy"
+        ],
+        "parameters": [
+          {
+            "name": "username",
+            "in": "query",
+            "description": "User to act as, required for app keys.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "id",
+            "in": "query",
+            "description": "Subscription ID.",
+            "schema": {
+              "type": "integer",
+              "format": "int64"
+            },
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Subscriptions.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Subscription"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "deprecated": true
+      }
+    },
+    "/tracker/listing/text": {
+      "get": {
+        "operationId": "legacyListCatalogs",
+        "summary": "List catalogs as text, e.g. 1. Push-ups: 30 times.",
+        "tags": [
+          "legacy"
+        ],
+        "parameters": [
+          {
+            "name": "username",
+            "in": "query",
+            "description": "User to act as, required for app keys.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only list catalogs having this tag, repeat to require several.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "One catalog per line.",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "deprecated": true
+      },
+      "post": {
+        "operationId": "legacyCreateCatalog",
+        "summary": "Add a catalog, then list catalogs as text.",
+        "tags": [
+          "legacy"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "username": {
+                    "type": "string"
+                  },
+                  "name": {
+                    "type": "string"
+                  },
+                  "unit": {
+                    "type": "string"
+                  }
+                },
+                "required": [
+                  "name"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "One catalog per line.",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "deprecated": true
+      }
+    },
+    "/tracker/marking/text": {
+      "post": {
+        "operationId": "legacyCreateEvent",
+        "summary": "Mark a catalog done, then list catalogs as text.",
+        "tags": [
+          "legacy"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "username": {
+                    "type": "string"
+                  },
+                  "catalogID": {
+                    "type": "integer"
+                  },
+                  "value": {
+                    "type": "number"
+                  }
+                },
+                "required": [
+                  "catalogID"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "One catalog per line.",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "deprecated": true
+      }
+    },
+    "/tracker/today/text": {
+      "get": {
+        "operationId": "legacyTrackerToday",
+        "summary": "Whether all catalogs having the tags are done today, as text, e.g. fitness: 2/3 done, left: 3. Run.",
+        "tags": [
+          "legacy"
+        ],
+        "parameters": [
+          {
+            "name": "username",
+            "in": "query",
+            "description": "User to act as, required for app keys.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only keep catalogs having this tag, repeat to require several.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "One line.",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "deprecated": true
+      }
+    },
+    "/tracker/heatmap/text": {
+      "get": {
+        "operationId": "legacyTrackerHeatmap",
+        "summary": "Get a heatmap of the user's events as Unicode blocks, one line per weekday.",
+        "tags": [
+          "legacy"
+        ],
+        "parameters": [
+          {
+            "name": "username",
+            "in": "query",
+            "description": "User to act as, required for app keys.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "catalogID",
+            "in": "query",
+            "description": "Only count this catalog.",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only keep catalogs having this tag, repeat to require several.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          },
+          {
+            "name": "weeks",
+            "in": "query",
+            "description": "Weeks covered up to this one, 53 by default.",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 53
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A summary line, then the heatmap.",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "404": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ],
+        "deprecated": true
+      }
+    },
+    "/tracker/stream": {
+      "get": {
+        "operationId": "streamTrackerEvents",
+        "summary": "Stream tracker events of a user as Server-Sent Events.",
+        "description": "Each change to a catalog is an event named by its type (added, marked, updated, removed or tagged) with a TrackerEvent as data. Comments are sent every 15 seconds to keep the connection open.",
+        "tags": [
+          "tracker"
+        ],
+        "parameters": [
+          {
+            "name": "username",
+            "in": "query",
+            "description": "User to act as, required for app keys.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Event stream.",
+            "content": {
+              "text/event-stream": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/Error"
+          },
+          "401": {
+            "$ref": "#/components/responses/Error"
+          },
+          "403": {
+            "$ref": "#/components/responses/Error"
+          },
+          "429": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": [
+          {
+            "apiKey": []
+          },
+          {
+            "bearer": []
+          }
+        ]
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "SteamGame": {
+        "type": "object",
+        "required": [
+          "appId",
+          "name",
+          "url",
+          "imgSrc",
+          "review",
+          "priceBefore",
+          "priceNow",
+          "discount",
+          "cc",
+          "currency"
+        ],
+        "properties": {
+          "appId": {
+            "type": "integer",
+            "description": "Steam app ID, 0 if unknown."
+          },
+          "name": {
+            "type": "string"
+          },
+          "url": {
+            "type": "string"
+          },
+          "imgSrc": {
+            "type": "string"
+          },
+          "review": {
+            "type": "string",
+            "description": "Review summary as shown by the store."
+          },
+          "priceBefore": {
+            "type": "number",
+            "format": "float"
+          },
+          "priceNow": {
+            "type": "number",
+            "format": "float"
+          },
+          "discount": {
+            "type": "string",
+            "description": "e.g. -40%."
+          },
+          "cc": {
+            "type": "string"
+          },
+          "currency": {
+            "type": "string"
+          }
+        }
+      },
+      "Review": {
+        "type": "object",
+        "required": [
+          "summary",
+          "percent",
+          "total"
+        ],
+        "properties": {
+          "summary": {
+            "type": "string"
+          },
+          "percent": {
+            "type": "integer"
+          },
+          "total": {
+            "type": "integer"
+          }
+        }
+      },
+      "PricePoint": {
+        "type": "object",
+        "required": [
+          "priceBefore",
+          "priceNow",
+          "discount",
+          "currency",
+          "recordedAt"
+        ],
+        "properties": {
+          "priceBefore": {
+            "type": "number",
+            "format": "float"
+          },
+          "priceNow": {
+            "type": "number",
+            "format": "float"
+          },
+          "discount": {
+            "type": "string"
+          },
+          "currency": {
+            "type": "string"
+          },
+          "recordedAt": {
+            "type": "string",
+            "format": "date-time"
+          }
+        }
+      },
+      "GameDetail": {
+        "type": "object",
+        "required": [
+          "appId",
+          "name",
+          "url",
+          "imgSrc",
+          "cc",
+          "discount",
+          "featured",
+          "review",
+          "priceHistory"
+        ],
+        "properties": {
+          "appId": {
+            "type": "integer"
+          },
+          "name": {
+            "type": "string"
+          },
+          "url": {
+            "type": "string"
+          },
+          "imgSrc": {
+            "type": "string"
+          },
+          "cc": {
+            "type": "string"
+          },
+          "discount": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/SteamGame"
+              }
+            ],
+            "nullable": true,
+            "description": "Current discount, null if not on sale."
+          },
+          "featured": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "nullable": true,
+            "description": "Featured categories listing the game."
+          },
+          "review": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/Review"
+              }
+            ],
+            "nullable": true
+          },
+          "priceHistory": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/PricePoint"
+            },
+            "nullable": true
+          }
+        }
+      },
+      "Subscription": {
+        "type": "object",
+        "required": [
+          "id",
+          "username",
+          "app",
+          "frequency",
+          "cc",
+          "format"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer",
+            "format": "int64",
+            "readOnly": true
+          },
+          "username": {
+            "type": "string",
+            "readOnly": true
+          },
+          "app": {
+            "type": "string",
+            "readOnly": true
+          },
+          "channel": {
+            "type": "string",
+            "description": "Deliver to this channel instead of the user."
+          },
+          "frequency": {
+            "type": "string",
+            "enum": [
+              "daily",
+              "weekly"
+            ],
+            "description": "Defaults to the digest frequency of the app."
+          },
+          "platform": {
+            "type": "string",
+            "enum": [
+              "win",
+              "mac",
+              "linux"
+            ]
+          },
+          "cc": {
+            "type": "string"
+          },
+          "maxPrice": {
+            "type": "number",
+            "format": "float"
+          },
+          "minReview": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 100
+          },
+          "format": {
+            "type": "string",
+            "enum": [
+              "text",
+              "cards"
+            ]
+          },
+          "lastSentAt": {
+            "type": "string",
+            "format": "date-time",
+            "readOnly": true,
+            "nullable": true
+          }
+        }
+      },
+      "Catalog": {
+        "type": "object",
+        "required": [
+          "id",
+          "name",
+          "done",
+          "tags"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "name": {
+            "type": "string"
+          },
+          "unit": {
+            "type": "string"
+          },
+          "done": {
+            "type": "boolean",
+            "description": "Whether it's marked done today."
+          },
+          "value": {
+            "type": "number",
+            "format": "float",
+            "description": "Today's value, if done."
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "Tags grouping catalogs, sorted."
+          }
+        }
+      },
+      "TrackerSummary": {
+        "type": "object",
+        "required": [
+          "tags",
+          "total",
+          "done",
+          "allDone",
+          "pending"
+        ],
+        "properties": {
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "Tags the catalogs were filtered by."
+          },
+          "total": {
+            "type": "integer",
+            "description": "Catalogs having the tags."
+          },
+          "done": {
+            "type": "integer",
+            "description": "Of them, those done today."
+          },
+          "allDone": {
+            "type": "boolean",
+            "description": "Whether nothing is left to do today."
+          },
+          "pending": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Catalog"
+            },
+            "description": "Catalogs not done yet, ordered by ID."
+          }
+        },
+        "description": "Today's progress over the catalogs having some tags."
+      },
+      "Heatmap": {
+        "type": "object",
+        "required": [
+          "from",
+          "to",
+          "total",
+          "max",
+          "days"
+        ],
+        "properties": {
+          "from": {
+            "type": "string",
+            "format": "date",
+            "description": "First day, a Sunday."
+          },
+          "to": {
+            "type": "string",
+            "format": "date",
+            "description": "Last day, today in the app's timezone."
+          },
+          "total": {
+            "type": "integer",
+            "description": "Events over all days."
+          },
+          "max": {
+            "type": "integer",
+            "description": "Events on the busiest day."
+          },
+          "days": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/HeatmapDay"
+            },
+            "description": "Every day from the first to the last."
+          }
+        },
+        "description": "Events per day over whole weeks, like a GitHub contribution graph."
+      },
+      "HeatmapDay": {
+        "type": "object",
+        "required": [
+          "date",
+          "count"
+        ],
+        "properties": {
+          "date": {
+            "type": "string",
+            "format": "date"
+          },
+          "count": {
+            "type": "integer"
+          }
+        }
+      },
+      "CalendarFeed": {
+        "type": "object",
+        "required": [
+          "token",
+          "url"
+        ],
+        "properties": {
+          "token": {
+            "type": "string",
+            "description": "Feed token, only shown once."
+          },
+          "url": {
+            "type": "string",
+            "description": "URL to subscribe to from calendar apps."
+          }
+        },
+        "description": "A user's iCalendar feed."
+      },
+      "CatalogRequest": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string",
+            "nullable": true
+          },
+          "unit": {
+            "type": "string",
+            "nullable": true
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "nullable": true,
+            "description": "Replaces the catalog's tags: up to 20 of lowercase letters, digits, '-' and '_'."
+          }
+        },
+        "description": "Omitted fields are left unchanged by updates."
+      },
+      "EventRequest": {
+        "type": "object",
+        "properties": {
+          "value": {
+            "type": "number"
+          }
+        }
+      },
+      "TrackerEvent": {
+        "type": "object",
+        "required": [
+          "type",
+          "username",
+          "app",
+          "catalogId",
+          "at"
+        ],
+        "properties": {
+          "type": {
+            "type": "string",
+            "enum": [
+              "added",
+              "marked",
+              "updated",
+              "removed",
+              "tagged"
+            ]
+          },
+          "username": {
+            "type": "string"
+          },
+          "app": {
+            "type": "string"
+          },
+          "catalogId": {
+            "type": "integer"
+          },
+          "name": {
+            "type": "string",
+            "description": "New name, for added and updated."
+          },
+          "unit": {
+            "type": "string",
+            "description": "New unit, for added and updated."
+          },
+          "value": {
+            "type": "number",
+            "description": "Marked value, for marked."
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "New tags, for tagged."
+          },
+          "at": {
+            "type": "string",
+            "format": "date-time"
+          }
+        }
+      },
+      "Checks": {
+        "type": "object",
+        "additionalProperties": {
+          "type": "string",
+          "enum": [
+            "ok",
+            "failed"
+          ]
+        },
+        "description": "ok or failed, per dependency. Failure details are only logged."
+      },
+      "Error": {
+        "type": "object",
+        "required": [
+          "code",
+          "message"
+        ],
+        "properties": {
+          "code": {
+            "type": "string",
+            "enum": [
+              "invalid_request",
+              "unauthorized",
+              "forbidden",
+              "not_found",
+              "method_not_allowed",
+              "conflict",
+              "rate_limited",
+              "quota_exceeded",
+              "internal_error"
+            ]
+          },
+          "message": {
+            "type": "string"
+          },
+          "details": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "string"
+            },
+            "description": "Problems of single fields, keyed by field name."
+          }
+        }
+      },
+      "ErrorEnvelope": {
+        "type": "object",
+        "required": [
+          "error"
+        ],
+        "properties": {
+          "error": {
+            "$ref": "#/components/schemas/Error"
+          },
+          "request_id": {
+            "type": "string"
+          }
+        }
+      },
+      "Webhook": {
+        "type": "object",
+        "required": [
+          "id",
+          "app",
+          "url",
+          "events",
+          "createdAt"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer",
+            "format": "int64",
+            "readOnly": true
+          },
+          "app": {
+            "type": "string",
+            "readOnly": true
+          },
+          "url": {
+            "type": "string",
+            "description": "Absolute http(s) URL deliveries are posted to."
+          },
+          "events": {
+            "type": "array",
+            "items": {
+              "type": "string",
+              "enum": [
+                "catalog.created",
+                "catalog.marked",
+                "streak.broken",
+                "steam.discount"
+              ]
+            }
+          },
+          "secret": {
+            "type": "string",
+            "readOnly": true,
+            "description": "Signing secret, only returned on registration."
+          },
+          "createdAt": {
+            "type": "string",
+            "format": "date-time",
+            "readOnly": true
+          }
+        }
+      },
+      "Delivery": {
+        "type": "object",
+        "required": [
+          "id",
+          "webhookId",
+          "event",
+          "status",
+          "attempts",
+          "createdAt"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "webhookId": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "event": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "pending",
+              "delivered",
+              "failed"
+            ]
+          },
+          "attempts": {
+            "type": "integer"
+          },
+          "responseStatus": {
+            "type": "integer",
+            "description": "HTTP status of the last attempt."
+          },
+          "lastError": {
+            "type": "string"
+          },
+          "nextAttemptAt": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true,
+            "description": "When it's retried, if pending."
+          },
+          "createdAt": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "deliveredAt": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true
+          }
+        }
+      },
+      "WebhookPayload": {
+        "type": "object",
+        "required": [
+          "event",
+          "occurredAt",
+          "data"
+        ],
+        "properties": {
+          "event": {
+            "type": "string"
+          },
+          "app": {
+            "type": "string",
+            "description": "App of the event, omitted for steam events."
+          },
+          "occurredAt": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "data": {
+            "type": "object",
+            "description": "A TrackerEvent for catalog events, a Streak for streak.broken, the region and new games for steam.discount."
+          }
+        }
+      },
+      "Streak": {
+        "type": "object",
+        "required": [
+          "catalogId",
+          "username",
+          "app",
+          "name",
+          "days",
+          "lastDone"
+        ],
+        "properties": {
+          "catalogId": {
+            "type": "integer"
+          },
+          "username": {
+            "type": "string"
+          },
+          "app": {
+            "type": "string"
+          },
+          "name": {
+            "type": "string"
+          },
+          "days": {
+            "type": "integer",
+            "description": "Days in a row the catalog was done."
+          },
+          "lastDone": {
+            "type": "string",
+            "format": "date-time"
+          }
+        }
+      },
+      "CronRun": {
+        "type": "object",
+        "required": [
+          "startedAt",
+          "finishedAt",
+          "owner"
+        ],
+        "properties": {
+          "startedAt": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "finishedAt": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true,
+            "description": "Null while running."
+          },
+          "error": {
+            "type": "string",
+            "description": "Why the run failed, absent if it succeeded."
+          },
+          "owner": {
+            "type": "string",
+            "description": "Dyno which ran it."
+          }
+        }
+      },
+      "CronTask": {
+        "type": "object",
+        "required": [
+          "name",
+          "schedule",
+          "nextRunAt",
+          "lastRun"
+        ],
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "schedule": {
+            "type": "string",
+            "description": "Cron expression, in the tracker timezone."
+          },
+          "nextRunAt": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "lastRun": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/CronRun"
+              }
+            ],
+            "nullable": true,
+            "description": "Null if it never ran."
+          }
+        }
+      },
+      "App": {
+        "type": "object",
+        "required": [
+          "name",
+          "digestFrequency",
+          "maxCatalogsPerUser",
+          "maxWebhooks",
+          "createdAt"
+        ],
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "timezone": {
+            "type": "string",
+            "description": "Timezone deciding when a tracking day starts for its users, the deployment's if absent."
+          },
+          "digestFrequency": {
+            "type": "string",
+            "enum": [
+              "daily",
+              "weekly"
+            ],
+            "description": "Frequency of digests subscribed without one."
+          },
+          "maxCatalogsPerUser": {
+            "type": "integer",
+            "description": "Quota, 0 means unlimited."
+          },
+          "maxWebhooks": {
+            "type": "integer",
+            "description": "Quota, 0 means unlimited."
+          },
+          "createdAt": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "disabledAt": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Set once disabled, keys of disabled apps are refused."
+          }
+        }
+      },
+      "AppRequest": {
+        "type": "object",
+        "description": "Omitted or null fields keep their value, or the default for new apps.",
+        "properties": {
+          "name": {
+            "type": "string",
+            "description": "Required when registering, lowercase letters, digits, - or _."
+          },
+          "timezone": {
+            "type": "string",
+            "nullable": true
+          },
+          "digestFrequency": {
+            "type": "string",
+            "enum": [
+              "daily",
+              "weekly"
+            ],
+            "nullable": true
+          },
+          "maxCatalogsPerUser": {
+            "type": "integer",
+            "nullable": true
+          },
+          "maxWebhooks": {
+            "type": "integer",
+            "nullable": true
+          },
+          "disabled": {
+            "type": "boolean",
+            "nullable": true
+          }
+        }
+      }
+    },
+    "responses": {
+      "Error": {
+        "description": "Error.",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorEnvelope"
+            }
+          },
+          "text/plain": {
+            "schema": {
+              "type": "string"
+            }
+          }
+        }
+      }
+    },
+    "securitySchemes": {
+      "apiKey": {
+        "type": "apiKey",
+        "in": "header",
+        "name": "X-API-Key"
+      },
+      "bearer": {
+        "type": "http",
+        "scheme": "bearer"
+      },
+      "adminToken": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "The admin_token secret of the deployment."
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,331 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"github.com/this-is-a-bot/bot/redis"
+)
+
+const (
+	// Attempts of a job before it's dead-lettered, unless set on the queue.
+	DefaultMaxAttempts = 5
+
+	// How long a reserved job stays invisible to other workers. Workers
+	// extend it while the job runs, so it only expires if the worker dies.
+	DefaultVisibilityTimeout = time.Minute
+
+	// Delay before the first retry, doubled for every further one.
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// Returned when asking for a dead job that doesn't exist.
+var ErrJobNotFound = errors.New("job not found")
+
+// Move due delayed jobs and jobs whose visibility timed out to the ready
+// list, then reserve the first ready job.
+//
+// KEYS: ready, delayed, inflight, attempts, jobs.
+// ARGV: now, visibility deadline (both in ms).
+// Returns {id, attempts, job} or nil if no job is ready.
+var reserveScript = redigo.NewScript(5, `
+for _, key in ipairs({KEYS[2], KEYS[3]}) do
+	local ids = redis.call('ZRANGEBYSCORE', key, '-inf', ARGV[1], 'LIMIT', 0, 100)
+	for _, id in ipairs(ids) do
+		redis.call('ZREM', key, id)
+		redis.call('RPUSH', KEYS[1], id)
+	end
+end
+local id = redis.call('LPOP', KEYS[1])
+if not id then
+	return false
+end
+redis.call('ZADD', KEYS[3], ARGV[2], id)
+local attempts = redis.call('HINCRBY', KEYS[4], id, 1)
+return {id, attempts, redis.call('HGET', KEYS[5], id)}
+`)
+
+// A unit of background work. Payload is JSON decoded by the handler of the
+// job's type.
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	EnqueuedAt  time.Time       `json:"enqueuedAt"`
+	MaxAttempts int             `json:"maxAttempts"`
+	LastError   string          `json:"lastError,omitempty"`
+
+	// Attempts so far, including the running one.
+	Attempts int `json:"attempts"`
+}
+
+// Decode the payload into v.
+func (job *Job) Decode(v interface{}) error {
+	return json.Unmarshal(job.Payload, v)
+}
+
+// Queue of jobs in Redis, shared by all dynos.
+//
+// Job data lives in a hash; IDs move between a ready list, a delayed sorted
+// set (scored by when they are due), an in-flight sorted set (scored by
+// their visibility deadline) and a dead list once they run out of attempts.
+// A live set holds the IDs of jobs that are ready, delayed or in flight.
+type Queue struct {
+	Name              string
+	MaxAttempts       int
+	VisibilityTimeout time.Duration
+
+	rs redis.RedisStore
+}
+
+// Create a queue with the default attempts and visibility timeout.
+func New(rs redis.RedisStore, name string) *Queue {
+	return &Queue{
+		Name:              name,
+		MaxAttempts:       DefaultMaxAttempts,
+		VisibilityTimeout: DefaultVisibilityTimeout,
+		rs:                rs,
+	}
+}
+
+func (q *Queue) key(name string) string {
+	return "queue:" + q.Name + ":" + name
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// Add a job to run as soon as possible, returning its ID.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}) (string, error) {
+	return q.EnqueueAt(ctx, jobType, payload, time.Time{})
+}
+
+// Add a job to run at the given time, or as soon as possible if it's zero.
+func (q *Queue) EnqueueAt(ctx context.Context, jobType string, payload interface{}, at time.Time) (string, error) {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	job := Job{
+		ID:          hex.EncodeToString(id),
+		Type:        jobType,
+		Payload:     js,
+		EnqueuedAt:  time.Now(),
+		MaxAttempts: q.MaxAttempts,
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+
+	conn := q.rs.GetConnection()
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("HSET", q.key("jobs"), job.ID, data)
+	conn.Send("SADD", q.key("live"), job.ID)
+	if at.IsZero() {
+		conn.Send("RPUSH", q.key("ready"), job.ID)
+	} else {
+		conn.Send("ZADD", q.key("delayed"), millis(at), job.ID)
+	}
+	if err := exec(conn); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// Whether the job is ready, delayed or running, false once it's done, dead
+// or unknown. Jobs enqueued before the live set existed count as unknown.
+func (q *Queue) Live(ctx context.Context, id string) (bool, error) {
+	conn := q.rs.GetConnection()
+	defer conn.Close()
+	return redigo.Bool(conn.Do("SISMEMBER", q.key("live"), id))
+}
+
+// Reserve the next ready job, nil if there's none.
+func (q *Queue) reserve() (*Job, error) {
+	conn := q.rs.GetConnection()
+	defer conn.Close()
+
+	now := time.Now()
+	values, err := redigo.Values(reserveScript.Do(
+		conn, q.key("ready"), q.key("delayed"), q.key("inflight"), q.key("attempts"),
+		q.key("jobs"), millis(now), millis(now.Add(q.VisibilityTimeout))))
+	if err == redigo.ErrNil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var id string
+	var attempts int
+	var data []byte
+	if _, err := redigo.Scan(values, &id, &attempts, &data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		// Data is gone, nothing to run.
+		return nil, q.remove(id)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("malformed job %s: %v", id, err)
+	}
+	job.Attempts = attempts
+	return &job, nil
+}
+
+// Push back the visibility deadline of a running job.
+func (q *Queue) extend(job *Job) error {
+	conn := q.rs.GetConnection()
+	defer conn.Close()
+	_, err := conn.Do(
+		"ZADD", q.key("inflight"), "XX", millis(time.Now().Add(q.VisibilityTimeout)), job.ID)
+	return err
+}
+
+// Remove a finished job.
+func (q *Queue) remove(id string) error {
+	conn := q.rs.GetConnection()
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("ZREM", q.key("inflight"), id)
+	conn.Send("SREM", q.key("live"), id)
+	conn.Send("HDEL", q.key("jobs"), id)
+	conn.Send("HDEL", q.key("attempts"), id)
+	return exec(conn)
+}
+
+// Record a failed attempt, scheduling a retry with backoff or moving the job
+// to the dead list once it's out of attempts. Returns whether it's dead.
+func (q *Queue) fail(job *Job, cause error) (bool, error) {
+	job.LastError = cause.Error()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return false, err
+	}
+
+	dead := job.Attempts >= job.MaxAttempts
+	conn := q.rs.GetConnection()
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("ZREM", q.key("inflight"), job.ID)
+	conn.Send("HSET", q.key("jobs"), job.ID, data)
+	if dead {
+		conn.Send("SREM", q.key("live"), job.ID)
+		conn.Send("LPUSH", q.key("dead"), job.ID)
+	} else {
+		conn.Send("ZADD", q.key("delayed"), millis(time.Now().Add(Backoff(job.Attempts))), job.ID)
+	}
+	return dead, exec(conn)
+}
+
+// Run the transaction started with MULTI. A command failing inside it
+// doesn't fail EXEC, its error is an element of the reply.
+func exec(conn redigo.Conn) error {
+	replies, err := redigo.Values(conn.Do("EXEC"))
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(redigo.Error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delay before retrying after the given number of failed attempts.
+func Backoff(attempts int) time.Duration {
+	backoff := baseBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// Number of jobs in each state.
+type Stats struct {
+	Ready    int `json:"ready"`
+	Delayed  int `json:"delayed"`
+	InFlight int `json:"inFlight"`
+	Dead     int `json:"dead"`
+}
+
+func (q *Queue) Stats() (Stats, error) {
+	conn := q.rs.GetConnection()
+	defer conn.Close()
+	conn.Send("LLEN", q.key("ready"))
+	conn.Send("ZCARD", q.key("delayed"))
+	conn.Send("ZCARD", q.key("inflight"))
+	conn.Send("LLEN", q.key("dead"))
+	conn.Flush()
+
+	var stats Stats
+	for _, n := range []*int{&stats.Ready, &stats.Delayed, &stats.InFlight, &stats.Dead} {
+		var err error
+		if *n, err = redigo.Int(conn.Receive()); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Get dead jobs, most recently failed first.
+func (q *Queue) DeadJobs(ctx context.Context, limit int) ([]Job, error) {
+	conn := q.rs.GetConnection()
+	defer conn.Close()
+	ids, err := redigo.Strings(conn.Do("LRANGE", q.key("dead"), 0, limit-1))
+	if err != nil || len(ids) == 0 {
+		return []Job{}, err
+	}
+
+	args := redigo.Args{}.Add(q.key("jobs")).AddFlat(ids)
+	values, err := redigo.ByteSlices(conn.Do("HMGET", args...))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Job, 0, len(values))
+	for _, data := range values {
+		var job Job
+		if data == nil || json.Unmarshal(data, &job) != nil {
+			continue
+		}
+		res = append(res, job)
+	}
+	return res, nil
+}
+
+// Give a dead job a fresh set of attempts.
+func (q *Queue) RetryDead(ctx context.Context, id string) error {
+	conn := q.rs.GetConnection()
+	defer conn.Close()
+	n, err := redigo.Int(conn.Do("LREM", q.key("dead"), 1, id))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrJobNotFound
+	}
+	conn.Send("MULTI")
+	conn.Send("HDEL", q.key("attempts"), id)
+	conn.Send("SADD", q.key("live"), id)
+	conn.Send("RPUSH", q.key("ready"), id)
+	return exec(conn)
+}
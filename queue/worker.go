@@ -0,0 +1,173 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/metrics"
+)
+
+// How often idle workers look for ready jobs.
+const DefaultPollInterval = time.Second
+
+var jobsProcessed = metrics.DefaultRegistry.NewCounterVec(
+	"bot_jobs_processed_total", "Background jobs run, by type and outcome.",
+	"type", "outcome")
+
+// Handler runs a job. Returning an error retries it later, until it's out
+// of attempts.
+type Handler func(ctx context.Context, job *Job) error
+
+// Worker runs jobs from a queue with handlers registered by job type.
+type Worker struct {
+	Queue        *Queue
+	Concurrency  int
+	PollInterval time.Duration
+
+	handlers map[string]Handler
+}
+
+// Create a worker running up to concurrency jobs at once.
+func NewWorker(q *Queue, concurrency int) *Worker {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Worker{
+		Queue:        q,
+		Concurrency:  concurrency,
+		PollInterval: DefaultPollInterval,
+		handlers:     make(map[string]Handler),
+	}
+}
+
+// Register the handler of a job type.
+func (w *Worker) Handle(jobType string, h Handler) {
+	w.handlers[jobType] = h
+}
+
+// Run jobs until ctx is done, then wait for the running ones to finish.
+// Jobs get their own context so shutdown doesn't abort them halfway, a job
+// still running when the process is killed is retried once its visibility
+// times out.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		job, err := w.Queue.reserve()
+		if err != nil {
+			logging.Errorf(ctx, "failed to reserve job from queue %s: %v", w.Queue.Name, err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.PollInterval):
+			}
+			continue
+		}
+		w.process(job)
+	}
+}
+
+// Run one reserved job and record its outcome.
+func (w *Worker) process(job *Job) {
+	ctx := context.Background()
+
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// Its worker died or hung on every attempt.
+		err = fmt.Errorf("visibility timed out after %d attempts", job.MaxAttempts)
+	} else if h, ok := w.handlers[job.Type]; !ok {
+		err = fmt.Errorf("no handler for job type %q", job.Type)
+	} else {
+		err = w.run(ctx, h, job)
+	}
+
+	if err == nil {
+		jobsProcessed.Inc(job.Type, "done")
+		if err := w.Queue.remove(job.ID); err != nil {
+			logging.Errorf(ctx, "failed to remove finished job %s: %v", job.ID, err)
+		}
+		return
+	}
+
+	dead, failErr := w.Queue.fail(job, err)
+	if failErr != nil {
+		logging.Errorf(ctx, "failed to record failure of job %s: %v", job.ID, failErr)
+	}
+	if dead {
+		jobsProcessed.Inc(job.Type, "dead")
+		logging.Errorf(ctx, "job %s (%s) is dead after %d attempts: %v", job.ID, job.Type, job.Attempts, err)
+	} else {
+		jobsProcessed.Inc(job.Type, "retried")
+		logging.Errorf(ctx, "job %s (%s) failed attempt %d: %v", job.ID, job.Type, job.Attempts, err)
+	}
+}
+
+// Call the handler, keeping the job invisible to other workers while it
+// runs. Panics are turned into errors.
+func (w *Worker) run(ctx context.Context, h Handler, job *Job) (err error) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(w.Queue.VisibilityTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := w.Queue.extend(job); err != nil {
+					logging.Errorf(ctx, "failed to extend visibility of job %s: %v", job.ID, err)
+				}
+			}
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
+
+// Export the number of jobs in each state as gauges.
+func (q *Queue) RegisterMetrics(reg *metrics.Registry) {
+	gauge := func(state string, fn func(s Stats) int) {
+		reg.NewGaugeFunc(
+			fmt.Sprintf("bot_queue_%s_%s_jobs", q.Name, state),
+			fmt.Sprintf("Jobs %s in queue %s.", state, q.Name),
+			func() float64 {
+				stats, err := q.Stats()
+				if err != nil {
+					return math.NaN()
+				}
+				return float64(fn(stats))
+			})
+	}
+	gauge("ready", func(s Stats) int { return s.Ready })
+	gauge("delayed", func(s Stats) int { return s.Delayed })
+	gauge("inflight", func(s Stats) int { return s.InFlight })
+	gauge("dead", func(s Stats) int { return s.Dead })
+}
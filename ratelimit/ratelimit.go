@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"github.com/this-is-a-bot/bot/apierror"
+	"github.com/this-is-a-bot/bot/auth"
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/redis"
+)
+
+const keyPrefix = "ratelimit:"
+
+// Sliding window log: every allowed request is a member of a sorted set
+// scored by its time in milliseconds. Running it as a script keeps the
+// check-and-add atomic across dynos.
+//
+// Returns {allowed, remaining, retry after in ms}.
+var slidingWindow = redigo.NewScript(1, `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, limit - count - 1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, 0, tonumber(oldest[2]) + window - now}
+`)
+
+// At most Requests requests are allowed within any Window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Outcome of one check.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Identify the caller a limit applies to.
+type KeyFunc func(r *http.Request) string
+
+// Limiter counts requests in Redis, so limits hold across all dynos.
+type Limiter struct {
+	rs redis.RedisStore
+}
+
+// Create a limiter on top of the given Redis store.
+func NewLimiter(rs redis.RedisStore) *Limiter {
+	return &Limiter{rs: rs}
+}
+
+// Record a request for key and report whether it's within the limit.
+func (l *Limiter) Allow(key string, limit Limit, now time.Time) (Result, error) {
+	conn := l.rs.GetConnection()
+	defer conn.Close()
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return Result{}, err
+	}
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	member := fmt.Sprintf("%d-%s", nowMs, hex.EncodeToString(nonce))
+
+	reply, err := redigo.Ints(slidingWindow.Do(
+		conn, keyPrefix+key, nowMs, int64(limit.Window/time.Millisecond),
+		limit.Requests, member))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    reply[0] == 1,
+		Limit:      limit.Requests,
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Millisecond,
+	}, nil
+}
+
+// Wrap a handler so each caller gets at most limit requests per window on
+// this route. Requests are let through if Redis is unavailable.
+func (l *Limiter) Limit(route string, limit Limit, keyFunc KeyFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		res, err := l.Allow(route+":"+keyFunc(r), limit, now)
+		if err != nil {
+			logging.Errorf(r.Context(), "rate limiter unavailable for %s: %v", route, err)
+			next(w, r)
+			return
+		}
+
+		reset := now.Add(limit.Window)
+		if !res.Allowed {
+			reset = now.Add(res.RetryAfter)
+		}
+		header := w.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+		if !res.Allowed {
+			// Round up, retrying a bit early would just be refused again.
+			retryAfter := (res.RetryAfter + time.Second - 1) / time.Second
+			header.Set("Retry-After", strconv.FormatInt(int64(retryAfter), 10))
+			apierror.Write(w, r, apierror.New(
+				http.StatusTooManyRequests, apierror.CodeRateLimited, "rate limit exceeded"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Key by the authenticated API key and user, falling back to the client IP
+// for anonymous requests.
+func ByIdentity(r *http.Request) string {
+	if id := auth.FromContext(r.Context()); id != nil {
+		return fmt.Sprintf("key:%d:%s", id.KeyID, id.Username)
+	}
+	return ByIP(r)
+}
+
+// Key by the API key the request carries, before it's looked up, falling
+// back to the client IP for requests without one. Keys are hashed so they
+// don't end up in Redis.
+func ByCredential(r *http.Request) string {
+	key := auth.PresentedKey(r)
+	if key == "" {
+		return ByIP(r)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "credential:" + hex.EncodeToString(sum[:])
+}
+
+// Key by the client IP. Heroku's router appends the address it got the
+// request from to X-Forwarded-For, earlier entries come from the client and
+// can't be trusted.
+func ByIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		entries := strings.Split(forwarded, ",")
+		return "ip:" + strings.TrimSpace(entries[len(entries)-1])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"sync/atomic"
 	"time"
 
 	redigo "github.com/garyburd/redigo/redis"
@@ -9,20 +10,71 @@ import (
 // RedisStore is the interface for underlying Redis persistence store.
 type RedisStore interface {
 	GetConnection() redigo.Conn
+	PubSub() (redigo.PubSubConn, error)
+	Stats() Stats
+}
+
+// Stats of the connection pool.
+type Stats struct {
+	// Connections allocated by the pool, including idle ones.
+	ActiveCount int
+
+	// Connections kept open in the pool while no caller holds them.
+	IdleCount int
 }
 
 type redigoStore struct {
+	url  string
 	pool *redigo.Pool
+
+	// Connections handed out and not closed yet, the pool doesn't report
+	// its idle ones.
+	inUse int64
+}
+
+// Connection counted as in use until it's closed.
+type countedConn struct {
+	redigo.Conn
+	inUse  *int64
+	closed int32
 }
 
-// NewStore creates a new Redis store.
+func (c *countedConn) Close() error {
+	if atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		atomic.AddInt64(c.inUse, -1)
+	}
+	return c.Conn.Close()
+}
+
+// Options tunes the connection pool of a store.
+type Options struct {
+	MaxIdle     int
+	MaxActive   int
+	IdleTimeout time.Duration
+}
+
+// NewStore creates a new Redis store with default pool options.
 func NewStore(url string) RedisStore {
-	// Build redigo connection.
-	pool := &redigo.Pool{
+	return NewStoreWithOptions(url, Options{
 		MaxIdle:     3,
 		IdleTimeout: 240 * time.Second,
+	})
+}
+
+// NewStoreWithOptions creates a new Redis store with the given pool options.
+func NewStoreWithOptions(url string, opts Options) RedisStore {
+	// Build redigo connection.
+	pool := &redigo.Pool{
+		MaxIdle:     opts.MaxIdle,
+		MaxActive:   opts.MaxActive,
+		IdleTimeout: opts.IdleTimeout,
 		Dial: func() (redigo.Conn, error) {
-			c, err := redigo.DialURL(url)
+			// Fail fast so callers can fall back when Redis is down.
+			c, err := redigo.DialURL(
+				url,
+				redigo.DialConnectTimeout(time.Second),
+				redigo.DialReadTimeout(time.Second),
+				redigo.DialWriteTimeout(time.Second))
 			if err != nil {
 				return nil, err
 			}
@@ -35,11 +87,41 @@ func NewStore(url string) RedisStore {
 	}
 
 	return &redigoStore{
+		url:  url,
 		pool: pool,
 	}
 }
 
 // GetConnection returns a redigo connection to interact with Redis store.
 func (rs *redigoStore) GetConnection() redigo.Conn {
-	return rs.pool.Get()
+	conn := rs.pool.Get()
+	if conn.Err() != nil {
+		// Failed to connect, the pool doesn't count it either.
+		return conn
+	}
+	atomic.AddInt64(&rs.inUse, 1)
+	return &countedConn{Conn: conn, inUse: &rs.inUse}
+}
+
+// PubSub dials a dedicated connection for subscriptions, outside the pool.
+// It has no read timeout as it waits for messages, the caller must close it.
+func (rs *redigoStore) PubSub() (redigo.PubSubConn, error) {
+	c, err := redigo.DialURL(
+		rs.url,
+		redigo.DialConnectTimeout(time.Second),
+		redigo.DialWriteTimeout(time.Second))
+	if err != nil {
+		return redigo.PubSubConn{}, err
+	}
+	return redigo.PubSubConn{Conn: c}, nil
+}
+
+// Stats returns the current connection pool stats.
+func (rs *redigoStore) Stats() Stats {
+	active := rs.pool.ActiveCount()
+	idle := active - int(atomic.LoadInt64(&rs.inUse))
+	if idle < 0 {
+		idle = 0
+	}
+	return Stats{ActiveCount: active, IdleCount: idle}
 }
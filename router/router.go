@@ -0,0 +1,126 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type contextKey struct{}
+
+// Router dispatches requests by method and path. A pattern is a list of
+// literal segments and `{name}` parameters, e.g.
+// "/v1/users/{user}/apps/{app}/catalogs/{id}".
+type Router struct {
+	// Called when no pattern matches the path, 404 by default.
+	NotFound http.HandlerFunc
+
+	// Called when a pattern matches the path but not the method, after the
+	// Allow header is set. 405 by default.
+	MethodNotAllowed http.HandlerFunc
+
+	routes []route
+}
+
+type route struct {
+	Route
+	segments []string
+	handler  http.HandlerFunc
+}
+
+// A registered method and pattern.
+type Route struct {
+	Method  string
+	Pattern string
+}
+
+// Create an empty router.
+func New() *Router {
+	return &Router{
+		NotFound: http.NotFound,
+		MethodNotAllowed: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		},
+	}
+}
+
+// Register a handler for the method and pattern. GET handlers also serve HEAD.
+func (rt *Router) Handle(method string, pattern string, h http.HandlerFunc) {
+	rt.routes = append(rt.routes, route{Route{method, pattern}, split(pattern), h})
+}
+
+// Get the registered routes, in order.
+func (rt *Router) Routes() []Route {
+	res := make([]Route, len(rt.routes))
+	for i, route := range rt.routes {
+		res[i] = route.Route
+	}
+	return res
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := split(r.URL.Path)
+	allowed := make(map[string]bool)
+	for _, route := range rt.routes {
+		params, ok := match(route.segments, segments)
+		if !ok {
+			continue
+		}
+		if route.Method == r.Method || (route.Method == "GET" && r.Method == "HEAD") {
+			ctx := context.WithValue(r.Context(), contextKey{}, params)
+			route.handler(w, r.WithContext(ctx))
+			return
+		}
+		allowed[route.Method] = true
+		if route.Method == "GET" {
+			allowed["HEAD"] = true
+		}
+	}
+
+	if len(allowed) == 0 {
+		rt.NotFound(w, r)
+		return
+	}
+	methods := make([]string, 0, len(allowed))
+	for method := range allowed {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	rt.MethodNotAllowed(w, r)
+}
+
+// Get a path parameter of the matched route, "" if there's no such parameter.
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(contextKey{}).(map[string]string)
+	return params[name]
+}
+
+// Split a path into segments, ignoring leading and trailing slashes.
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// Match path segments against a pattern, returning the parameters.
+func match(pattern []string, segments []string) (map[string]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, p := range pattern {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[p[1:len(p)-1]] = segments[i]
+		} else if p != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
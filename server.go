@@ -1,193 +1,403 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"sort"
-	"strconv"
-	"strings"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/this-is-a-bot/bot/apierror"
+	"github.com/this-is-a-bot/bot/apps"
+	"github.com/this-is-a-bot/bot/auth"
+	"github.com/this-is-a-bot/bot/calendar"
+	"github.com/this-is-a-bot/bot/config"
+	"github.com/this-is-a-bot/bot/cron"
+	"github.com/this-is-a-bot/bot/digest"
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/metrics"
+	"github.com/this-is-a-bot/bot/migrate"
+	"github.com/this-is-a-bot/bot/openapi"
+	"github.com/this-is-a-bot/bot/queue"
+	"github.com/this-is-a-bot/bot/ratelimit"
 	"github.com/this-is-a-bot/bot/redis"
+	"github.com/this-is-a-bot/bot/router"
 	"github.com/this-is-a-bot/bot/steam"
 	"github.com/this-is-a-bot/bot/tracker"
+	"github.com/this-is-a-bot/bot/webhook"
 )
 
-// Global instance.
-var (
-	db *sql.DB
-	rs redis.RedisStore
-)
+// Server holds the dependencies shared by all handlers.
+type server struct {
+	cfg      *config.Config
+	db       *sql.DB
+	rs       redis.RedisStore
+	steam    steam.Store
+	tracker  tracker.Store
+	hub      *tracker.Hub
+	jobs     *queue.Queue
+	cron     *cron.Scheduler
+	webhooks *webhook.Dispatcher
+	limiter  *ratelimit.Limiter
+	keys     auth.Keys
+
+	// Set once SIGTERM is received, fails readiness so no new traffic is sent.
+	shuttingDown int32
 
-func setup() {
-	var err error
-	if os.Getenv("ENV") == "HEROKU" {
-		db, err = sql.Open("postgres", os.Getenv("DATABASE_URL"))
-		rs = redis.NewStore(os.Getenv("REDIS_URL"))
-	} else {
-		// Local dev.
-		db, err = sql.Open("postgres", "dbname=bot sslmode=disable")
-		rs = redis.NewStore("redis://127.0.0.1:6379")
+	// Closed once SIGTERM is received, ends long-lived streams.
+	closing chan struct{}
+}
+
+// Create a server backed by Postgres and Redis.
+func newServer(cfg *config.Config, db *sql.DB, rs redis.RedisStore) *server {
+	hub := tracker.NewHub(rs)
+	jobs := queue.New(rs, "default")
+	webhooks := webhook.NewDispatcher(db, jobs)
+	steamStore := steam.NewPostgresStore(db)
+	publishers := tracker.Publishers{hub}
+	// Without webhooks nothing drains deliveries, so none are emitted.
+	if cfg.Enabled("webhooks") {
+		steamStore = steam.NewNotifyingStore(steamStore, webhooks.DiscountListener())
+		publishers = append(publishers, webhooks.TrackerPublisher())
+	}
+	trackerStore := tracker.NewPublishingStore(tracker.NewPostgresStore(db), publishers)
+	s := &server{
+		cfg:      cfg,
+		db:       db,
+		rs:       rs,
+		steam:    steam.NewCachedStore(steamStore, rs, cfg.CacheTTL.Duration),
+		tracker:  trackerStore,
+		hub:      hub,
+		jobs:     jobs,
+		webhooks: webhooks,
+		limiter:  ratelimit.NewLimiter(rs),
+		keys:     auth.NewPostgresKeys(db),
+		closing:  make(chan struct{}),
 	}
+	s.cron = s.schedule()
+	return s
+}
+
+// Load the config and open database & redis.
+func setup() (*config.Config, *sql.DB, redis.RedisStore) {
+	configPath := flag.String(
+		"config", os.Getenv("BOT_CONFIG"), "path to a JSON config file")
+	flag.Parse()
 
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		// Fatal error, stop.
 		panic(err)
 	}
-}
+	log.Printf("Effective config:\n%s\n", cfg)
 
-func main() {
-	http.HandleFunc("/", handleIndex)
-	http.HandleFunc("/steam/discounts", handleSteamDiscounts)
-	http.HandleFunc("/steam/featured", handleSteamFeatured)
-	http.HandleFunc("/tracker/listing/text", handleTrackerListingText)
-	http.HandleFunc("/tracker/marking/text", handleTrackerMarkingText)
-
-	// Init database & redis.
-	setup()
-	defer db.Close()
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		panic(err)
+	}
+	db.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.DB.MaxIdleConns)
 
-	hostport := fmt.Sprintf(":%s", os.Getenv("PORT"))
-	log.Printf("Server running on %s\n", hostport)
-	log.Fatal(http.ListenAndServe(hostport, nil))
-}
+	rs := redis.NewStoreWithOptions(cfg.RedisURL, redis.Options{
+		MaxIdle:     cfg.Redis.MaxIdle,
+		MaxActive:   cfg.Redis.MaxActive,
+		IdleTimeout: cfg.Redis.IdleTimeout.Duration,
+	})
 
-// Dummy index handler.
-func handleIndex(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintln(w, "Welcome! I am a bot.")
+	// Already validated by the config.
+	if err = tracker.SetTimezone(cfg.Timezone); err != nil {
+		panic(err)
+	}
+	return cfg, db, rs
 }
 
-/* Steam. */
+func main() {
+	// Init config, database & redis.
+	cfg, db, rs := setup()
+	defer db.Close()
 
-// Return a list of discounted steam games in JSON format.
-func handleSteamDiscounts(w http.ResponseWriter, r *http.Request) {
-	games, err := steam.GetDiscounts(db)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// The worker process type runs background work instead of serving.
+	args := flag.Args()
+	if len(args) == 1 && args[0] == "worker" {
+		newServer(cfg, db, rs).runWorker()
 		return
 	}
 
-	js, err := json.Marshal(games)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if len(args) > 0 {
+		if err := runCommand(cfg, db, rs, args); err != nil {
+			log.Println(err)
+			db.Close()
+			os.Exit(1)
+		}
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(js)
-}
-
-// Return a list of featured steam games in JSON format
-func handleSteamFeatured(w http.ResponseWriter, r *http.Request) {
-	feature := r.FormValue("feature")
-	games, err := steam.GetFeatured(db, feature)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if cfg.Enabled("auto_migrate") {
+		if _, err := migrate.Up(db); err != nil {
+			log.Printf("Failed to migrate: %v\n", err)
+			db.Close()
+			os.Exit(1)
+		}
 	}
 
-	js, err := json.Marshal(games)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	s := newServer(cfg, db, rs)
+	metrics.DefaultRegistry.RegisterDBStats(db)
+	metrics.DefaultRegistry.RegisterRedisStats(rs)
+	s.jobs.RegisterMetrics(metrics.DefaultRegistry)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(js)
-}
+	// Background work stops as soon as shutdown starts.
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
 
-/* Tracker. */
+	go s.hub.Run(ctx)
+	// Handy locally, deployments run a separate worker process.
+	if cfg.Enabled("inline_worker") {
+		go s.work(ctx)
+	}
 
-type ByID []tracker.Catalog
+	hostport := fmt.Sprintf(":%s", cfg.Port)
+	httpServer := &http.Server{Addr: hostport, Handler: s.routes()}
 
-func (a ByID) Len() int           { return len(a) }
-func (a ByID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ByID) Less(i, j int) bool { return a[i].ID < a[j].ID }
+	// On SIGTERM (Heroku dyno restarts) or Ctrl-C, stop accepting connections
+	// and give in-flight requests some time to finish.
+	drained := make(chan struct{})
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+		sig := <-sigs
+		log.Printf("Received %v, shutting down\n", sig)
+		atomic.StoreInt32(&s.shuttingDown, 1)
+		close(s.closing)
+		stop()
 
-// Return plain texts of tracking list.
-func handleTrackerListingText(w http.ResponseWriter, r *http.Request) {
-	username, app := r.FormValue("username"), r.FormValue("app")
-	switch r.Method {
-	case "POST":
-		// POST method for adding new tracking.
-		name, unit := r.PostFormValue("name"), r.PostFormValue("unit")
-		if name == "" {
-			http.Error(w, "'name' field is required", http.StatusBadRequest)
-			return
-		}
-		_, err := tracker.AddTracking(db, username, app, name, unit)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		shutdownCtx, cancel := context.WithTimeout(
+			context.Background(), cfg.ShutdownTimeout.Duration)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to drain requests: %v\n", err)
 		}
+		close(drained)
+	}()
+
+	log.Printf("Server running on %s\n", hostport)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		log.Printf("Server failed: %v\n", err)
+		stop()
+		db.Close()
+		os.Exit(1)
 	}
+	<-drained
+	log.Println("Server stopped")
+}
 
-	catalogs, err := tracker.GetTrackingCatalogs(db, username, app)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	} else if len(catalogs) == 0 {
-		http.Error(w, "no catalogs for such user", http.StatusNotFound)
-		return
+// Register all routes.
+func (s *server) routes() http.Handler {
+	rt := router.New()
+	rt.NotFound = logging.Middleware("not_found", apierror.Handler(
+		apierror.NotFound("no such route")))
+	rt.MethodNotAllowed = logging.Middleware("method_not_allowed", apierror.Handler(
+		apierror.New(http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "method not allowed")))
+	handle := func(method string, pattern string, route string, h http.HandlerFunc) {
+		rt.Handle(method, pattern, logging.Middleware(route, metrics.Instrument(route, h)))
 	}
 
-	sort.Sort(ByID(catalogs))
-	res := make([]string, 0)
-	for _, catalog := range catalogs {
-		s := fmt.Sprintf("%d. %s", catalog.ID, catalog.Name)
-		if catalog.Done {
-			if catalog.Value > 0 {
-				s += fmt.Sprintf(": %v", catalog.Value)
-				if catalog.Unit != "" {
-					s += " " + catalog.Unit
-				}
-			} else {
-				s += ": done"
-			}
-		} else {
-			s += ": x"
-		}
-		res = append(res, s)
+	publicLimit := ratelimit.Limit{Requests: 120, Window: time.Minute}
+	// Each limiter name counts requests against one limit, so reads and
+	// writes of a resource use separate names.
+	readLimit := ratelimit.Limit{Requests: 60, Window: time.Minute}
+	writeLimit := ratelimit.Limit{Requests: 20, Window: time.Minute}
+	authLimit := ratelimit.Limit{Requests: 300, Window: time.Minute}
+	public := func(route string, h http.HandlerFunc) http.HandlerFunc {
+		return s.limiter.Limit(route, publicLimit, ratelimit.ByIP, h)
+	}
+	// Caps what a single key can cost before it's looked up. Keyed by the
+	// key rather than the IP, adapters serve many users from one address.
+	authenticated := func(h http.HandlerFunc) http.HandlerFunc {
+		return s.limiter.Limit("authenticated", authLimit, ratelimit.ByCredential, h)
+	}
+	// Legacy routes name the user in the `username` form value, /v1 routes
+	// in the path.
+	legacy := func(route string, limit ratelimit.Limit, h http.HandlerFunc) http.HandlerFunc {
+		return authenticated(auth.Require(s.keys,
+			s.inApp(s.limiter.Limit(route, limit, ratelimit.ByIdentity, h))))
+	}
+	appOnly := func(route string, limit ratelimit.Limit, h http.HandlerFunc) http.HandlerFunc {
+		return authenticated(auth.RequireApp(s.keys, pathTarget,
+			s.inApp(s.limiter.Limit(route, limit, ratelimit.ByIdentity, h))))
+	}
+	user := func(route string, limit ratelimit.Limit, h http.HandlerFunc) http.HandlerFunc {
+		return authenticated(auth.RequireTarget(s.keys, pathTarget,
+			s.inApp(s.limiter.Limit(route, limit, ratelimit.ByIdentity, h))))
+	}
+	admin := func(h http.HandlerFunc) http.HandlerFunc {
+		return public("admin", auth.RequireAdmin(s.cfg.Secrets["admin_token"], h))
 	}
 
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(strings.Join(res, "\n")))
-}
+	handle("GET", "/", "index", handleIndex)
+	handle("GET", "/healthz", "healthz", s.handleHealthz)
+	handle("GET", "/readyz", "readyz", s.handleReadyz)
+	handle("GET", "/metrics", "metrics", admin(metrics.DefaultRegistry.Handler()))
+	handle("GET", "/openapi.json", "openapi", openapi.Handler())
+	handle("GET", "/v1/admin/cron", "v1_admin_cron", admin(s.handleCronStatus))
+	handle("GET", "/v1/admin/apps", "v1_admin_list_apps", admin(s.handleListApps))
+	handle("POST", "/v1/admin/apps", "v1_admin_create_app", admin(s.handleCreateApp))
+	handle("GET", "/v1/admin/apps/{app}", "v1_admin_get_app", admin(s.handleGetApp))
+	handle("PATCH", "/v1/admin/apps/{app}", "v1_admin_update_app", admin(s.handleUpdateApp))
 
-// Mark event done, then return plain texts of tracking list.
-func handleTrackerMarkingText(w http.ResponseWriter, r *http.Request) {
-	// Only allow POST.
-	if r.Method != "POST" {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
+	// Versioned API.
+	const catalogs = "/v1/users/{user}/apps/{app}/catalogs"
+	const digests = "/v1/users/{user}/apps/{app}/digests"
+	const webhooks = "/v1/apps/{app}/webhooks"
+	handle("GET", "/v1/apps/{app}", "v1_get_own_app",
+		appOnly("apps", readLimit, s.handleGetOwnApp))
+	handle("GET", "/v1/steam/discounts", "v1_steam_discounts",
+		public("steam_discounts", s.handleSteamDiscounts))
+	handle("GET", "/v1/steam/featured", "v1_steam_featured",
+		public("steam_featured", s.handleSteamFeatured))
+	handle("GET", "/v1/steam/games/{appid}", "v1_steam_game",
+		public("steam_game", s.handleSteamGame))
+	handle("GET", digests, "v1_list_digests",
+		user("steam_digests", readLimit, s.handleListDigests))
+	handle("POST", digests, "v1_create_digest",
+		user("steam_digests_write", writeLimit, s.handleCreateDigest))
+	handle("DELETE", digests+"/{id}", "v1_delete_digest",
+		user("steam_digests_write", writeLimit, s.handleDeleteDigest))
+	if s.cfg.Enabled("webhooks") {
+		handle("GET", webhooks, "v1_list_webhooks",
+			appOnly("webhooks", readLimit, s.handleListWebhooks))
+		handle("POST", webhooks, "v1_create_webhook",
+			appOnly("webhooks_write", writeLimit, s.handleCreateWebhook))
+		handle("DELETE", webhooks+"/{id}", "v1_delete_webhook",
+			appOnly("webhooks_write", writeLimit, s.handleDeleteWebhook))
+		handle("GET", webhooks+"/{id}/deliveries", "v1_list_webhook_deliveries",
+			appOnly("webhooks", readLimit, s.handleListWebhookDeliveries))
+	}
+	handle("GET", catalogs, "v1_list_catalogs",
+		user("tracker_listing", readLimit, s.handleListCatalogs))
+	handle("POST", catalogs, "v1_create_catalog",
+		user("tracker_listing_write", writeLimit, s.handleCreateCatalog))
+	handle("GET", catalogs+"/{id}", "v1_get_catalog",
+		user("tracker_listing", readLimit, s.handleGetCatalog))
+	handle("PATCH", catalogs+"/{id}", "v1_update_catalog",
+		user("tracker_listing_write", writeLimit, s.handleUpdateCatalog))
+	handle("DELETE", catalogs+"/{id}", "v1_delete_catalog",
+		user("tracker_listing_write", writeLimit, s.handleDeleteCatalog))
+	handle("POST", catalogs+"/{id}/events", "v1_create_event",
+		user("tracker_marking", writeLimit, s.handleCreateEvent))
+	handle("GET", "/v1/users/{user}/apps/{app}/today", "v1_tracker_today",
+		user("tracker_listing", readLimit, s.handleTrackerToday))
+	handle("POST", "/v1/users/{user}/apps/{app}/calendar", "v1_create_calendar_feed",
+		user("tracker_listing_write", writeLimit, s.handleCreateCalendarFeed))
+	handle("DELETE", "/v1/users/{user}/apps/{app}/calendar", "v1_delete_calendar_feed",
+		user("tracker_listing_write", writeLimit, s.handleDeleteCalendarFeed))
+	handle("GET", "/v1/calendars/{token}/habits.ics", "v1_calendar_feed",
+		public("calendar_feed", s.handleCalendarFeed))
+	for _, ext := range []string{"", ".svg", ".png", ".txt"} {
+		handle("GET", catalogs+"/{id}/heatmap"+ext, "v1_catalog_heatmap",
+			user("tracker_listing", readLimit, s.handleCatalogHeatmap))
+		handle("GET", "/v1/users/{user}/apps/{app}/heatmap"+ext, "v1_user_heatmap",
+			user("tracker_listing", readLimit, s.handleUserHeatmap))
 	}
 
-	catalogIDText, valueText := r.PostFormValue("catalogID"), r.PostFormValue("value")
-	catalogID, err := strconv.Atoi(catalogIDText)
-	if err != nil {
-		http.Error(w, "'catalogID' must be an integer", http.StatusBadRequest)
-		return
+	// Unversioned routes, kept for existing chat adapters.
+	handle("GET", "/steam/discounts", "steam_discounts",
+		public("steam_discounts", s.handleSteamDiscounts))
+	handle("GET", "/steam/featured", "steam_featured",
+		public("steam_featured", s.handleSteamFeatured))
+	handle("GET", "/steam/game/{appid}", "steam_game",
+		public("steam_game", s.handleSteamGame))
+	handle("GET", "/steam/digests", "steam_digests",
+		legacy("steam_digests", readLimit, s.handleSteamDigests))
+	for _, method := range []string{"POST", "DELETE"} {
+		handle(method, "/steam/digests", "steam_digests",
+			legacy("steam_digests_write", writeLimit, s.handleSteamDigests))
 	}
+	handle("GET", "/tracker/listing/text", "tracker_listing",
+		legacy("tracker_listing", readLimit, s.handleTrackerListingText))
+	handle("POST", "/tracker/listing/text", "tracker_listing",
+		legacy("tracker_listing_write", writeLimit, s.handleTrackerAddingText))
+	handle("POST", "/tracker/marking/text", "tracker_marking",
+		legacy("tracker_marking", writeLimit, s.handleTrackerMarkingText))
+	handle("GET", "/tracker/today/text", "tracker_today",
+		legacy("tracker_listing", readLimit, s.handleTrackerTodayText))
+	handle("GET", "/tracker/heatmap/text", "tracker_heatmap",
+		legacy("tracker_listing", readLimit, s.handleTrackerHeatmapText))
+	handle("GET", "/tracker/stream", "tracker_stream",
+		legacy("tracker_stream", readLimit, s.handleTrackerStream))
+	return rt
+}
 
-	var value float64 = 0
-	if valueText != "" {
-		value, err = strconv.ParseFloat(valueText, 64)
-		if err != nil {
-			http.Error(w, "'value' must be a float", http.StatusBadRequest)
+// Target of /v1 routes, the app and user named in the path.
+func pathTarget(r *http.Request) (string, string) {
+	return router.Param(r, "app"), router.Param(r, "user")
+}
+
+// Wrap an authenticated handler so it runs in the caller's app, as loaded
+// with the key: disabled or unregistered apps are refused, the app is
+// attached to the context and tracker days start in its timezone.
+func (s *server) inApp(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := auth.FromContext(r.Context())
+		a := id.Tenant
+		if a == nil || !a.Enabled() {
+			writeError(w, r, apierror.Forbidden("app %s is not registered or disabled", id.App))
 			return
 		}
+
+		ctx := tracker.WithTimezone(apps.NewContext(r.Context(), a), a.Location())
+		next(w, r.WithContext(ctx))
 	}
+}
+
+// Write err to the client, mapping errors of the domain packages to API
+// errors. Anything unexpected is logged and hidden behind a 500.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch e := err.(type) {
+	case *steam.InvalidFeatureError:
+		err = apierror.InvalidField("feature", e.Error())
+	case *steam.UnknownCurrencyError:
+		err = apierror.InvalidField("currency", e.Error())
+	}
+	switch err {
+	case tracker.ErrCatalogNotFound, steam.ErrGameNotFound, digest.ErrSubscriptionNotFound,
+		webhook.ErrWebhookNotFound, apps.ErrAppNotFound, tracker.ErrAppNotFound,
+		calendar.ErrFeedNotFound:
+		err = apierror.NotFound("%v", err)
+	case apps.ErrAppExists:
+		err = apierror.Conflict("%v", err)
+	}
+	apierror.Write(w, r, err)
+}
 
-	err = tracker.MarkDone(db, catalogID, value)
+// Decode a JSON request body into v, answering 400 if it's malformed.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, r, apierror.Invalid("malformed JSON body: %v", err))
+		return false
+	}
+	return true
+}
+
+// Write v as JSON with the given status.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	js, err := json.Marshal(v)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
 
-	// FIXME: A hack to reuse the code by changing the method to GET to avoid creating tracking.
-	r.Method = "GET"
-	handleTrackerListingText(w, r)
+// Dummy index handler.
+func handleIndex(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "Welcome! I am a bot.")
 }
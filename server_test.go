@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/this-is-a-bot/bot/apps"
+	"github.com/this-is-a-bot/bot/auth"
+	"github.com/this-is-a-bot/bot/config"
+	"github.com/this-is-a-bot/bot/openapi"
+	"github.com/this-is-a-bot/bot/queue"
+	"github.com/this-is-a-bot/bot/ratelimit"
+	"github.com/this-is-a-bot/bot/redis"
+	"github.com/this-is-a-bot/bot/router"
+	"github.com/this-is-a-bot/bot/steam"
+	"github.com/this-is-a-bot/bot/tracker"
+	"github.com/this-is-a-bot/bot/webhook"
+)
+
+const (
+	testAdminToken = "admin-secret"
+	testAppKey     = "bot_app"
+	testUserKey    = "bot_alice"
+)
+
+// A server on in-memory stores. Postgres and Redis point to a closed port:
+// the rate limiter lets requests through, handlers needing the database
+// are only exercised up to their validation.
+func newTestServer(t *testing.T) *server {
+	cfg := config.Default()
+	cfg.Secrets["admin_token"] = testAdminToken
+
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 dbname=bot sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := redis.NewStore("redis://127.0.0.1:1")
+
+	games := steam.NewMemoryStore()
+	ctx := context.Background()
+	game := steam.SteamGame{
+		AppID: 10, Name: "Counter-Strike", URL: "https://store.steampowered.com/app/10/",
+		Review: "Very Positive", PriceBefore: 9.99, PriceNow: 4.99, Discount: "-50%",
+	}
+	if err := games.SaveDiscounts(ctx, steam.DefaultCountryCode, []steam.SteamGame{game}); err != nil {
+		t.Fatal(err)
+	}
+	if err := games.SaveFeatured(ctx, "win", steam.DefaultCountryCode, []steam.SteamGame{game}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &apps.App{Name: "chat", DigestFrequency: "daily", CreatedAt: time.Now()}
+	jobs := queue.New(rs, "test")
+	s := &server{
+		cfg:      cfg,
+		db:       db,
+		rs:       rs,
+		steam:    games,
+		tracker:  tracker.NewMemoryStore(),
+		hub:      tracker.NewHub(rs),
+		jobs:     jobs,
+		webhooks: webhook.NewDispatcher(db, jobs),
+		limiter:  ratelimit.NewLimiter(rs),
+		keys: auth.MemoryKeys{
+			testAppKey:  {KeyID: 1, App: "chat", Tenant: app},
+			testUserKey: {KeyID: 2, App: "chat", Username: "alice", Tenant: app},
+		},
+		closing: make(chan struct{}),
+	}
+	s.cron = s.schedule()
+	return s
+}
+
+// One request and the documented response it should get.
+type specCase struct {
+	operation string
+	method    string
+	path      string
+	auth      string
+	form      url.Values
+	body      string
+	status    int
+}
+
+func TestHandlersMatchSpec(t *testing.T) {
+	spec := loadSpec(t)
+	s := newTestServer(t)
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+	defer close(s.closing)
+
+	const user = "/v1/users/alice/apps/chat"
+	const admin = "Bearer " + testAdminToken
+	const appKey = "Bearer " + testAppKey
+	const userKey = "Bearer " + testUserKey
+	cases := []specCase{
+		{"getIndex", "GET", "/", "", nil, "", 200},
+		{"getHealth", "GET", "/healthz", "", nil, "", 200},
+		{"getReadiness", "GET", "/readyz", "", nil, "", 503},
+		{"getMetrics", "GET", "/metrics", admin, nil, "", 200},
+		{"getMetrics", "GET", "/metrics", "", nil, "", 401},
+		{"getOpenAPI", "GET", "/openapi.json", "", nil, "", 200},
+
+		{"listCronTasks", "GET", "/v1/admin/cron", "", nil, "", 401},
+		{"listApps", "GET", "/v1/admin/apps", "Bearer wrong", nil, "", 401},
+		{"createApp", "POST", "/v1/admin/apps", admin, nil, `{"name": "My Bot"}`, 400},
+		{"getApp", "GET", "/v1/admin/apps/chat", "", nil, "", 401},
+		{"updateApp", "PATCH", "/v1/admin/apps/chat", admin, nil, `{"timezone":`, 400},
+
+		{"listSteamDiscounts", "GET", "/v1/steam/discounts", "", nil, "", 200},
+		{"listSteamDiscounts", "GET", "/v1/steam/discounts?cc=usa", "", nil, "", 400},
+		{"listSteamFeatured", "GET", "/v1/steam/featured?feature=win", "", nil, "", 200},
+		{"listSteamFeatured", "GET", "/v1/steam/featured?feature=amiga", "", nil, "", 400},
+		{"getSteamGame", "GET", "/v1/steam/games/10", "", nil, "", 200},
+		{"getSteamGame", "GET", "/v1/steam/games/11", "", nil, "", 404},
+		{"getSteamGame", "GET", "/v1/steam/games/abc", "", nil, "", 400},
+		{"legacyListSteamDiscounts", "GET", "/steam/discounts", "", nil, "", 200},
+		{"legacyListSteamFeatured", "GET", "/steam/featured?feature=win", "", nil, "", 200},
+		{"legacyGetSteamGame", "GET", "/steam/game/10", "", nil, "", 200},
+		{"legacyGetSteamGame", "GET", "/steam/game/10?currency=XXX", "", nil, "", 400},
+
+		{"getOwnApp", "GET", "/v1/apps/chat", appKey, nil, "", 200},
+		{"getOwnApp", "GET", "/v1/apps/chat", userKey, nil, "", 403},
+		{"getOwnApp", "GET", "/v1/apps/chat", "", nil, "", 401},
+
+		{"listDigests", "GET", user + "/digests", "", nil, "", 401},
+		{"listDigests", "GET", "/v1/users/bob/apps/chat/digests", userKey, nil, "", 403},
+		{"createDigest", "POST", user + "/digests", userKey, nil, `{"frequency": "hourly"}`, 400},
+		{"deleteDigest", "DELETE", user + "/digests/abc", userKey, nil, "", 400},
+		{"legacyListDigests", "GET", "/steam/digests", "", nil, "", 401},
+		{"legacyCreateDigest", "POST", "/steam/digests", userKey,
+			url.Values{"maxPrice": {"cheap"}}, "", 400},
+		{"legacyDeleteDigest", "DELETE", "/steam/digests?id=abc", userKey, nil, "", 400},
+
+		{"listWebhooks", "GET", "/v1/apps/chat/webhooks", "", nil, "", 401},
+		{"createWebhook", "POST", "/v1/apps/chat/webhooks", appKey, nil,
+			`{"url": "http://169.254.169.254/latest", "events": ["catalog.created"]}`, 400},
+		{"deleteWebhook", "DELETE", "/v1/apps/chat/webhooks/abc", appKey, nil, "", 400},
+		{"listWebhookDeliveries", "GET", "/v1/apps/chat/webhooks/abc/deliveries", appKey, nil, "", 400},
+
+		{"listCatalogs", "GET", user + "/catalogs", userKey, nil, "", 200},
+		{"createCatalog", "POST", user + "/catalogs", userKey, nil,
+			`{"name": "Run", "unit": "km", "tags": ["Health"]}`, 201},
+		{"createCatalog", "POST", user + "/catalogs", userKey, nil, `{"unit": "km"}`, 400},
+		{"getCatalog", "GET", user + "/catalogs/1", userKey, nil, "", 200},
+		{"getCatalog", "GET", user + "/catalogs/2", userKey, nil, "", 404},
+		{"updateCatalog", "PATCH", user + "/catalogs/1", userKey, nil, `{"name": "Jog"}`, 200},
+		{"createEvent", "POST", user + "/catalogs/1/events", userKey, nil, `{"value": 5}`, 201},
+		{"createEvent", "POST", user + "/catalogs/2/events", userKey, nil, "", 404},
+		{"listCatalogs", "GET", user + "/catalogs?tag=health", userKey, nil, "", 200},
+		{"getTrackerToday", "GET", user + "/today", userKey, nil, "", 200},
+		{"getCatalogHeatmap", "GET", user + "/catalogs/1/heatmap", userKey, nil, "", 200},
+		{"getCatalogHeatmap", "GET", user + "/catalogs/1/heatmap?weeks=0", userKey, nil, "", 400},
+		{"getCatalogHeatmapSvg", "GET", user + "/catalogs/1/heatmap.svg", userKey, nil, "", 200},
+		{"getCatalogHeatmapPng", "GET", user + "/catalogs/1/heatmap.png", userKey, nil, "", 200},
+		{"getCatalogHeatmapText", "GET", user + "/catalogs/1/heatmap.txt", userKey, nil, "", 200},
+		{"getUserHeatmap", "GET", user + "/heatmap", userKey, nil, "", 200},
+		{"getUserHeatmapSvg", "GET", user + "/heatmap.svg", userKey, nil, "", 200},
+		{"getUserHeatmapPng", "GET", user + "/heatmap.png", userKey, nil, "", 200},
+		{"getUserHeatmapText", "GET", user + "/heatmap.txt?tag=health", userKey, nil, "", 200},
+		{"deleteCatalog", "DELETE", user + "/catalogs/1", userKey, nil, "", 204},
+		{"deleteCatalog", "DELETE", user + "/catalogs/1", userKey, nil, "", 404},
+
+		{"createCalendarFeed", "POST", user + "/calendar", "", nil, "", 401},
+		{"deleteCalendarFeed", "DELETE", user + "/calendar", "", nil, "", 401},
+		{"getCalendarFeed", "GET", "/v1/calendars/unknown/habits.ics", "", nil, "", 404},
+
+		{"legacyCreateCatalog", "POST", "/tracker/listing/text", appKey,
+			url.Values{"username": {"alice"}, "name": {"Read"}, "unit": {"pages"}}, "", 200},
+		{"legacyListCatalogs", "GET", "/tracker/listing/text?username=alice", appKey, nil, "", 200},
+		{"legacyListCatalogs", "GET", "/tracker/listing/text", appKey, nil, "", 400},
+		{"legacyCreateEvent", "POST", "/tracker/marking/text", appKey,
+			url.Values{"username": {"alice"}, "catalogID": {"2"}, "value": {"20"}}, "", 200},
+		{"legacyCreateEvent", "POST", "/tracker/marking/text", appKey,
+			url.Values{"username": {"alice"}, "catalogID": {"x"}}, "", 400},
+		{"legacyTrackerToday", "GET", "/tracker/today/text?username=alice", appKey, nil, "", 200},
+		{"legacyTrackerHeatmap", "GET", "/tracker/heatmap/text?username=alice", appKey, nil, "", 200},
+		{"streamTrackerEvents", "GET", "/tracker/stream?username=alice", appKey, nil, "", 200},
+	}
+
+	covered := make(map[string]bool)
+	for _, c := range cases {
+		covered[c.operation] = true
+		op := spec.operation(c.operation)
+		if op == nil {
+			t.Errorf("%s: no such operation in the spec", c.operation)
+			continue
+		}
+		if strings.ToLower(c.method) != op.method {
+			t.Errorf("%s: spec has method %s, case uses %s", c.operation, op.method, c.method)
+		}
+		res, body := do(t, ts, c)
+		if res.StatusCode != c.status {
+			t.Errorf("%s %s = %d, want %d: %s", c.method, c.path, res.StatusCode, c.status, body)
+			continue
+		}
+		if err := spec.checkResponse(op, res, body); err != nil {
+			t.Errorf("%s %s: %v", c.method, c.path, err)
+		}
+	}
+
+	for _, op := range spec.operations {
+		if !covered[op.id] {
+			t.Errorf("operation %s (%s %s) isn't exercised", op.id, op.method, op.path)
+		}
+	}
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	spec := loadSpec(t)
+	documented := make(map[string]bool)
+	for _, op := range spec.operations {
+		documented[op.method+" "+op.path] = true
+	}
+
+	routes := newTestServer(t).routes().(*router.Router).Routes()
+	for _, route := range routes {
+		if key := strings.ToLower(route.Method) + " " + route.Pattern; !documented[key] {
+			t.Errorf("route %s %s isn't in the spec", route.Method, route.Pattern)
+		}
+	}
+}
+
+func TestWebhooksDisabled(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.Features["webhooks"] = false
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	c := specCase{method: "GET", path: "/v1/apps/chat/webhooks", auth: "Bearer " + testAppKey}
+	if res, body := do(t, ts, c); res.StatusCode != http.StatusNotFound {
+		t.Errorf("GET %s = %d, want 404: %s", c.path, res.StatusCode, body)
+	}
+}
+
+// Send the request of a case. Streams are read up to the first event.
+func do(t *testing.T, ts *httptest.Server, c specCase) (*http.Response, []byte) {
+	var body string
+	if c.form != nil {
+		body = c.form.Encode()
+	} else {
+		body = c.body
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequest(c.method, ts.URL+c.path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if c.form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else if c.body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.auth != "" {
+		req.Header.Set("Authorization", c.auth)
+	}
+
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", c.method, c.path, err)
+	}
+	defer res.Body.Close()
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		buf := make([]byte, 64)
+		n, _ := res.Body.Read(buf)
+		return res, buf[:n]
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("%s %s: %v", c.method, c.path, err)
+	}
+	return res, data
+}
+
+/* A small OpenAPI 3 validator, covering what the spec uses. */
+
+type specDoc struct {
+	doc        map[string]interface{}
+	operations []*specOperation
+}
+
+type specOperation struct {
+	id        string
+	method    string
+	path      string
+	responses map[string]interface{}
+}
+
+func loadSpec(t *testing.T) *specDoc {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(openapi.Spec), &doc); err != nil {
+		t.Fatalf("spec isn't valid JSON: %v", err)
+	}
+	spec := &specDoc{doc: doc}
+	for path, item := range doc["paths"].(map[string]interface{}) {
+		for method, op := range item.(map[string]interface{}) {
+			if method == "parameters" {
+				continue
+			}
+			o := op.(map[string]interface{})
+			spec.operations = append(spec.operations, &specOperation{
+				id:        o["operationId"].(string),
+				method:    method,
+				path:      path,
+				responses: o["responses"].(map[string]interface{}),
+			})
+		}
+	}
+	sort.Slice(spec.operations, func(i, j int) bool {
+		return spec.operations[i].id < spec.operations[j].id
+	})
+	return spec
+}
+
+func (spec *specDoc) operation(id string) *specOperation {
+	for _, op := range spec.operations {
+		if op.id == id {
+			return op
+		}
+	}
+	return nil
+}
+
+// Resolve a local reference like "#/components/schemas/Catalog".
+func (spec *specDoc) resolve(node map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var cur interface{} = spec.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			cur = cur.(map[string]interface{})[part]
+		}
+		node = cur.(map[string]interface{})
+	}
+}
+
+// Check that the status is documented, and the body has a documented
+// content type and matches its schema.
+func (spec *specDoc) checkResponse(op *specOperation, res *http.Response, body []byte) error {
+	status := fmt.Sprint(res.StatusCode)
+	response, ok := op.responses[status].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("status %s isn't documented for %s", status, op.id)
+	}
+	response = spec.resolve(response)
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("undocumented body %q", body)
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("bad content type %q", res.Header.Get("Content-Type"))
+	}
+	media, ok := content[mediaType].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("content type %s isn't documented for %s %s", mediaType, op.id, status)
+	}
+	if mediaType != "application/json" {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("malformed JSON %q: %v", body, err)
+	}
+	schema, _ := media["schema"].(map[string]interface{})
+	return spec.validate(schema, value, "body")
+}
+
+// Validate a decoded JSON value against a schema.
+func (spec *specDoc) validate(schema map[string]interface{}, value interface{}, at string) error {
+	if schema == nil {
+		return nil
+	}
+	schema = spec.resolve(schema)
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if err := spec.validate(sub.(map[string]interface{}), value, at); err != nil {
+				return err
+			}
+		}
+	}
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || schema["type"] == nil {
+			return nil
+		}
+		return fmt.Errorf("%s is null", at)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == value
+		}
+		if !found {
+			return fmt.Errorf("%s = %v isn't one of %v", at, value, enum)
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s isn't an object", at)
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				return fmt.Errorf("%s lacks required %s", at, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, v := range obj {
+			if prop, ok := props[name].(map[string]interface{}); ok {
+				if err := spec.validate(prop, v, at+"."+name); err != nil {
+					return err
+				}
+			} else if extra, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				if err := spec.validate(extra, v, at+"."+name); err != nil {
+					return err
+				}
+			} else if props != nil {
+				return fmt.Errorf("%s has undocumented property %s", at, name)
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s isn't an array", at)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, v := range arr {
+			if err := spec.validate(items, v, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s isn't a string", at)
+		}
+		layout := map[string]string{"date-time": time.RFC3339, "date": "2006-01-02"}[fmt.Sprint(schema["format"])]
+		if _, err := time.Parse(layout, str); layout != "" && err != nil {
+			return fmt.Errorf("%s = %q isn't a %s", at, str, schema["format"])
+		}
+	case "integer", "number":
+		num, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s isn't a number", at)
+		}
+		if schema["type"] == "integer" && num != math.Trunc(num) {
+			return fmt.Errorf("%s = %v isn't an integer", at, num)
+		}
+		if min, ok := schema["minimum"].(float64); ok && num < min {
+			return fmt.Errorf("%s = %v is below %v", at, num, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && num > max {
+			return fmt.Errorf("%s = %v is above %v", at, num, max)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s isn't a boolean", at)
+		}
+	}
+	return nil
+}
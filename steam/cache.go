@@ -0,0 +1,124 @@
+package steam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/redis"
+)
+
+// How long a cached listing is served before it's read again.
+const DefaultCacheTTL = 5 * time.Minute
+
+const (
+	cacheKeyPrefix     = "steam:cache:"
+	cacheGenerationKey = "steam:cache:generation"
+)
+
+// Read-through cache of steam listings backed by Redis, wrapping another
+// store. Only listings are cached, writes go through and invalidate.
+//
+// Cached entries are namespaced by a generation counter, bumping the counter
+// invalidates every entry at once on all dynos; stale entries simply expire.
+// Whenever Redis is unavailable the cache falls back to the wrapped store.
+type Cache struct {
+	Store
+	rs  redis.RedisStore
+	ttl time.Duration
+}
+
+// Wrap a store with a cache on top of the given Redis store.
+func NewCachedStore(store Store, rs redis.RedisStore, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &Cache{Store: store, rs: rs, ttl: ttl}
+}
+
+func (c *Cache) GetDiscounts(ctx context.Context, cc string) ([]SteamGame, error) {
+	key := fmt.Sprintf("discounts:%s", normalizeCountryCode(cc))
+	return c.fetch(ctx, key, func() ([]SteamGame, error) {
+		return c.Store.GetDiscounts(ctx, cc)
+	})
+}
+
+func (c *Cache) GetFeatured(ctx context.Context, feature string, cc string) ([]SteamGame, error) {
+	key := fmt.Sprintf(
+		"featured:%s:%s", normalizeFeature(feature), normalizeCountryCode(cc))
+	return c.fetch(ctx, key, func() ([]SteamGame, error) {
+		return c.Store.GetFeatured(ctx, feature, cc)
+	})
+}
+
+func (c *Cache) SaveDiscounts(ctx context.Context, cc string, games []SteamGame) error {
+	if err := c.Store.SaveDiscounts(ctx, cc, games); err != nil {
+		return err
+	}
+	c.invalidateAfterSave(ctx)
+	return nil
+}
+
+func (c *Cache) SaveFeatured(ctx context.Context, feature string, cc string, games []SteamGame) error {
+	if err := c.Store.SaveFeatured(ctx, feature, cc, games); err != nil {
+		return err
+	}
+	c.invalidateAfterSave(ctx)
+	return nil
+}
+
+// Drop all cached listings.
+func (c *Cache) Invalidate() error {
+	conn := c.rs.GetConnection()
+	defer conn.Close()
+
+	_, err := conn.Do("INCR", cacheGenerationKey)
+	return err
+}
+
+// Invalidate once a save committed. The save succeeded either way, so a
+// failure is only logged: stale entries are served until their TTL ends.
+func (c *Cache) invalidateAfterSave(ctx context.Context) {
+	if err := c.Invalidate(); err != nil {
+		logging.Errorf(ctx, "failed to invalidate steam cache, entries expire within %v: %v", c.ttl, err)
+	}
+}
+
+func (c *Cache) fetch(ctx context.Context, key string, load func() ([]SteamGame, error)) ([]SteamGame, error) {
+	conn := c.rs.GetConnection()
+	defer conn.Close()
+
+	generation, err := redigo.Int64(conn.Do("GET", cacheGenerationKey))
+	if err != nil && err != redigo.ErrNil {
+		logging.Errorf(ctx, "steam cache unavailable, reading from database: %v", err)
+		return load()
+	}
+	key = fmt.Sprintf("%s%d:%s", cacheKeyPrefix, generation, key)
+
+	data, err := redigo.Bytes(conn.Do("GET", key))
+	if err == nil {
+		var games []SteamGame
+		if err = json.Unmarshal(data, &games); err == nil {
+			return games, nil
+		}
+		logging.Errorf(ctx, "steam cache entry %s is corrupted: %v", key, err)
+	} else if err != redigo.ErrNil {
+		logging.Errorf(ctx, "steam cache unavailable, reading from database: %v", err)
+		return load()
+	}
+
+	games, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(games); err == nil {
+		_, err = conn.Do("SET", key, data, "EX", int(c.ttl/time.Second))
+	}
+	if err != nil {
+		logging.Errorf(ctx, "failed to fill steam cache entry %s: %v", key, err)
+	}
+	return games, nil
+}
@@ -0,0 +1,85 @@
+package steam
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Returned when a currency is missing from the exchange rate table.
+type UnknownCurrencyError struct {
+	Currency string
+}
+
+func (e *UnknownCurrencyError) Error() string {
+	return fmt.Sprintf("unknown currency %q", e.Currency)
+}
+
+// Exchange rates keyed by upper-cased ISO 4217 code. Each rate is the amount
+// of that currency worth one unit of the base currency (USD).
+type ExchangeRates map[string]float64
+
+// Convert an amount between two currencies.
+func (rates ExchangeRates) Convert(amount float32, from string, to string) (float32, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, nil
+	}
+
+	fromRate, ok := rates[from]
+	if !ok || fromRate <= 0 {
+		return 0, &UnknownCurrencyError{from}
+	}
+	toRate, ok := rates[to]
+	if !ok || toRate <= 0 {
+		return 0, &UnknownCurrencyError{to}
+	}
+
+	// Round to cents, prices are displayed with two decimals.
+	converted := float64(amount) / fromRate * toRate
+	return float32(math.Floor(converted*100+0.5) / 100), nil
+}
+
+// Convert the prices of given games to the currency `to` in place.
+func (rates ExchangeRates) ConvertGames(games []SteamGame, to string) error {
+	if _, ok := rates[strings.ToUpper(to)]; !ok {
+		return &UnknownCurrencyError{to}
+	}
+
+	var err error
+	for i := range games {
+		game := &games[i]
+		if game.PriceBefore, err = rates.Convert(game.PriceBefore, game.Currency, to); err != nil {
+			return err
+		}
+		if game.PriceNow, err = rates.Convert(game.PriceNow, game.Currency, to); err != nil {
+			return err
+		}
+		game.Currency = strings.ToUpper(to)
+	}
+	return nil
+}
+
+// Convert all prices of a game detail to the currency `to` in place.
+func (rates ExchangeRates) ConvertGameDetail(detail *GameDetail, to string) error {
+	if detail.Discount != nil {
+		games := []SteamGame{*detail.Discount}
+		if err := rates.ConvertGames(games, to); err != nil {
+			return err
+		}
+		detail.Discount = &games[0]
+	}
+
+	var err error
+	for i := range detail.PriceHistory {
+		point := &detail.PriceHistory[i]
+		if point.PriceBefore, err = rates.Convert(point.PriceBefore, point.Currency, to); err != nil {
+			return err
+		}
+		if point.PriceNow, err = rates.Convert(point.PriceNow, point.Currency, to); err != nil {
+			return err
+		}
+		point.Currency = strings.ToUpper(to)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package steam
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	appIDPattern  = regexp.MustCompile(`/app/(\d+)`)
+	reviewPattern = regexp.MustCompile(`(\d+)% of the ([\d,]+) user reviews`)
+
+	// Returned when there is no data at all for the requested app ID.
+	ErrGameNotFound = errors.New("game not found")
+)
+
+// Parsed form of the Steam review summary, e.g.
+// "Very Positive<br>92% of the 3,456 user reviews for this game are positive."
+type Review struct {
+	Summary string `json:"summary"`
+	Percent int    `json:"percent"`
+	Total   int    `json:"total"`
+}
+
+// One recorded price of a game in a region.
+type PricePoint struct {
+	PriceBefore float32   `json:"priceBefore"`
+	PriceNow    float32   `json:"priceNow"`
+	Discount    string    `json:"discount"`
+	Currency    string    `json:"currency"`
+	RecordedAt  time.Time `json:"recordedAt"`
+}
+
+// Everything known about a single game in a region.
+type GameDetail struct {
+	AppID        int          `json:"appId"`
+	Name         string       `json:"name"`
+	URL          string       `json:"url"`
+	ImgSrc       string       `json:"imgSrc"`
+	CountryCode  string       `json:"cc"`
+	Discount     *SteamGame   `json:"discount"`
+	Featured     []string     `json:"featured"`
+	Review       *Review      `json:"review"`
+	PriceHistory []PricePoint `json:"priceHistory"`
+}
+
+// Extract the Steam app ID from a store link such as
+// "https://store.steampowered.com/app/730/CounterStrike/".
+func ParseAppID(link string) (int, error) {
+	match := appIDPattern.FindStringSubmatch(link)
+	if match == nil {
+		return 0, fmt.Errorf("no app ID in link %q", link)
+	}
+	return strconv.Atoi(match[1])
+}
+
+// Parse a Steam review summary. Returns nil if the text doesn't look like one.
+func ParseReview(text string) *Review {
+	if text == "" {
+		return nil
+	}
+
+	review := &Review{Summary: text}
+	if i := strings.Index(text, "<br>"); i >= 0 {
+		review.Summary = strings.TrimSpace(text[:i])
+	}
+	if match := reviewPattern.FindStringSubmatch(text); match != nil {
+		review.Percent, _ = strconv.Atoi(match[1])
+		review.Total, _ = strconv.Atoi(strings.Replace(match[2], ",", "", -1))
+	}
+	return review
+}
+
+func newGameDetail(appID int, cc string) *GameDetail {
+	return &GameDetail{
+		AppID:        appID,
+		CountryCode:  cc,
+		Featured:     make([]string, 0),
+		PriceHistory: make([]PricePoint, 0),
+	}
+}
+
+// Take name, link, image and review from the current discount.
+func (detail *GameDetail) setDiscount(game SteamGame) {
+	detail.Discount = &game
+	detail.Name, detail.URL, detail.ImgSrc = game.Name, game.URL, game.ImgSrc
+	detail.Review = ParseReview(game.Review)
+}
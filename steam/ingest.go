@@ -0,0 +1,230 @@
+package steam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/this-is-a-bot/bot/logging"
+)
+
+// Public storefront API of the Steam store.
+const DefaultStoreAPI = "https://store.steampowered.com/api"
+
+// Public review summaries of the Steam store, per app ID.
+const DefaultReviewsAPI = "https://store.steampowered.com/appreviews"
+
+// Featured categories of the storefront API saved under each feature.
+// The API has no VR or free to play listing, those are left as they are.
+var categoryFeatures = map[string]string{
+	"top_sellers":    "top_sellers",
+	"new_releases":   "new_releases",
+	"coming_soon":    "coming_soon",
+	"featured_win":   "win",
+	"featured_mac":   "mac",
+	"featured_linux": "linux",
+}
+
+// Ingester fetches listings from the Steam storefront API and saves them.
+type Ingester struct {
+	Store      Store
+	BaseURL    string
+	ReviewsURL string
+	Client     *http.Client
+}
+
+// Create an ingester saving to the given store.
+func NewIngester(store Store) *Ingester {
+	return &Ingester{
+		Store:      store,
+		BaseURL:    DefaultStoreAPI,
+		ReviewsURL: DefaultReviewsAPI,
+		Client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Item of a storefront listing. Prices are in cents.
+type storeItem struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	Discounted      bool   `json:"discounted"`
+	DiscountPercent int    `json:"discount_percent"`
+	OriginalPrice   *int   `json:"original_price"`
+	FinalPrice      int    `json:"final_price"`
+	Currency        string `json:"currency"`
+	HeaderImage     string `json:"header_image"`
+	LargeCapsule    string `json:"large_capsule_image"`
+}
+
+func (item storeItem) game() SteamGame {
+	game := SteamGame{
+		AppID:    item.ID,
+		Name:     item.Name,
+		URL:      fmt.Sprintf("https://store.steampowered.com/app/%d/", item.ID),
+		ImgSrc:   item.HeaderImage,
+		PriceNow: float32(item.FinalPrice) / 100,
+		Currency: item.Currency,
+	}
+	if game.ImgSrc == "" {
+		game.ImgSrc = item.LargeCapsule
+	}
+	game.PriceBefore = game.PriceNow
+	if item.OriginalPrice != nil {
+		game.PriceBefore = float32(*item.OriginalPrice) / 100
+	}
+	if item.Discounted && item.DiscountPercent > 0 {
+		game.Discount = fmt.Sprintf("-%d%%", item.DiscountPercent)
+	}
+	return game
+}
+
+// Fetch the discounts and featured listings of a country and save them,
+// returning how many games each listing got. Empty listings are not saved,
+// so a bad answer doesn't wipe the previous crawl.
+func (in *Ingester) Ingest(ctx context.Context, cc string) (map[string]int, error) {
+	cc = normalizeCountryCode(cc)
+	counts := make(map[string]int)
+	// Games show up in several listings, their review is fetched once.
+	reviews := make(map[int]string)
+
+	// Categories hold their items in an object, the featured listing in a
+	// plain list.
+	var categories map[string]json.RawMessage
+	if err := in.get(ctx, "featuredcategories", cc, &categories); err != nil {
+		return counts, err
+	}
+	var featured map[string]json.RawMessage
+	if err := in.get(ctx, "featured", cc, &featured); err != nil {
+		return counts, err
+	}
+
+	var specials struct {
+		Items []storeItem `json:"items"`
+	}
+	if raw, ok := categories["specials"]; ok && json.Unmarshal(raw, &specials) == nil {
+		if games := toGames(specials.Items); len(games) > 0 {
+			in.addReviews(ctx, games, reviews)
+			if err := in.Store.SaveDiscounts(ctx, cc, games); err != nil {
+				return counts, err
+			}
+			counts["discounts"] = len(games)
+		}
+	}
+
+	for key, feature := range categoryFeatures {
+		var items []storeItem
+		if raw, ok := featured[key]; ok {
+			if json.Unmarshal(raw, &items) != nil {
+				continue
+			}
+		} else if raw, ok := categories[key]; ok {
+			var category struct {
+				Items []storeItem `json:"items"`
+			}
+			if json.Unmarshal(raw, &category) != nil {
+				continue
+			}
+			items = category.Items
+		}
+
+		games := toGames(items)
+		if len(games) == 0 {
+			continue
+		}
+		in.addReviews(ctx, games, reviews)
+		if err := in.Store.SaveFeatured(ctx, feature, cc, games); err != nil {
+			return counts, err
+		}
+		counts["featured_"+feature] = len(games)
+	}
+	return counts, nil
+}
+
+func toGames(items []storeItem) []SteamGame {
+	games := make([]SteamGame, 0, len(items))
+	seen := make(map[int]bool)
+	for _, item := range items {
+		// Listings repeat games and contain bundles without an ID.
+		if item.ID == 0 || item.Name == "" || seen[item.ID] {
+			continue
+		}
+		seen[item.ID] = true
+		games = append(games, item.game())
+	}
+	return games
+}
+
+// Fill in the review summary of games, using and filling the summaries
+// already fetched. A game whose summary can't be fetched keeps none.
+func (in *Ingester) addReviews(ctx context.Context, games []SteamGame, reviews map[int]string) {
+	for i := range games {
+		review, ok := reviews[games[i].AppID]
+		if !ok {
+			var err error
+			if review, err = in.review(ctx, games[i].AppID); err != nil {
+				logging.Errorf(ctx, "failed to fetch reviews of steam app %d: %v", games[i].AppID, err)
+			}
+			reviews[games[i].AppID] = review
+		}
+		games[i].Review = review
+	}
+}
+
+// Fetch the review summary of an app, in the format of the store pages
+// ParseReview reads. "" if the app has no reviews.
+func (in *Ingester) review(ctx context.Context, appID int) (string, error) {
+	query := url.Values{
+		"json": {"1"}, "language": {"all"}, "purchase_type": {"all"}, "num_per_page": {"0"},
+	}
+	req, err := http.NewRequest(
+		"GET", fmt.Sprintf("%s/%d?%s", in.ReviewsURL, appID, query.Encode()), nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := in.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("steam appreviews answered %s", res.Status)
+	}
+
+	var body struct {
+		Summary struct {
+			Description   string `json:"review_score_desc"`
+			TotalPositive int    `json:"total_positive"`
+			TotalReviews  int    `json:"total_reviews"`
+		} `json:"query_summary"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	summary := body.Summary
+	if summary.TotalReviews == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("%s<br>%d%% of the %d user reviews for this game are positive.",
+		summary.Description, summary.TotalPositive*100/summary.TotalReviews,
+		summary.TotalReviews), nil
+}
+
+func (in *Ingester) get(ctx context.Context, endpoint string, cc string, v interface{}) error {
+	query := url.Values{"cc": {cc}, "l": {"english"}}
+	req, err := http.NewRequest("GET", in.BaseURL+"/"+endpoint+"/?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := in.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("steam %s answered %s", endpoint, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
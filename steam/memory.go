@@ -0,0 +1,171 @@
+package steam
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps steam listings in memory, for tests and local experiments.
+type MemoryStore struct {
+	// Clock used to timestamp price history.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	discounts map[string][]SteamGame
+	featured  map[string]map[string][]SteamGame
+	history   map[string][]PricePoint
+	rates     ExchangeRates
+}
+
+// Create an empty in-memory store knowing only the USD exchange rate.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		Now:       time.Now,
+		discounts: make(map[string][]SteamGame),
+		featured:  make(map[string]map[string][]SteamGame),
+		history:   make(map[string][]PricePoint),
+		rates:     ExchangeRates{"USD": 1},
+	}
+}
+
+// Set the exchange rate of a currency against USD.
+func (s *MemoryStore) SetExchangeRate(currency string, rate float64) {
+	s.mu.Lock()
+	s.rates[strings.ToUpper(currency)] = rate
+	s.mu.Unlock()
+}
+
+func (s *MemoryStore) GetDiscounts(ctx context.Context, cc string) ([]SteamGame, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return copyGames(s.discounts[normalizeCountryCode(cc)]), nil
+}
+
+func (s *MemoryStore) GetFeatured(ctx context.Context, feature string, cc string) ([]SteamGame, error) {
+	feature = normalizeFeature(feature)
+	if !IsValidFeature(feature) {
+		return nil, &InvalidFeatureError{feature}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return copyGames(s.featured[normalizeCountryCode(cc)][feature]), nil
+}
+
+func (s *MemoryStore) GetGame(ctx context.Context, appID int, cc string) (*GameDetail, error) {
+	cc = normalizeCountryCode(cc)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	detail := newGameDetail(appID, cc)
+	for _, game := range s.discounts[cc] {
+		if game.AppID == appID {
+			detail.setDiscount(game)
+			break
+		}
+	}
+
+	features := make([]string, 0)
+	for feature, games := range s.featured[cc] {
+		for _, game := range games {
+			if game.AppID != appID {
+				continue
+			}
+			features = append(features, feature)
+			if detail.Name == "" {
+				detail.Name, detail.URL, detail.ImgSrc = game.Name, game.URL, game.ImgSrc
+			}
+			break
+		}
+	}
+	sort.Strings(features)
+	detail.Featured = features
+
+	detail.PriceHistory = append(detail.PriceHistory, s.history[historyKey(appID, cc)]...)
+	if detail.Name == "" && len(detail.PriceHistory) == 0 {
+		return nil, ErrGameNotFound
+	}
+	return detail, nil
+}
+
+func (s *MemoryStore) GetExchangeRates(ctx context.Context) (ExchangeRates, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rates := make(ExchangeRates, len(s.rates))
+	for currency, rate := range s.rates {
+		rates[currency] = rate
+	}
+	return rates, nil
+}
+
+func (s *MemoryStore) SaveDiscounts(ctx context.Context, cc string, games []SteamGame) error {
+	cc = normalizeCountryCode(cc)
+	games = prepareForSave(games, cc)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.discounts[cc] = games
+	s.recordPrices(games)
+	return nil
+}
+
+func (s *MemoryStore) SaveFeatured(ctx context.Context, feature string, cc string, games []SteamGame) error {
+	if !IsValidFeature(feature) {
+		return &InvalidFeatureError{feature}
+	}
+	cc = normalizeCountryCode(cc)
+	games = prepareForSave(games, cc)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.featured[cc] == nil {
+		s.featured[cc] = make(map[string][]SteamGame)
+	}
+	s.featured[cc][feature] = games
+	s.recordPrices(games)
+	return nil
+}
+
+// Same rules as Postgres: skip games without app ID, only record changes.
+// Must hold mu.
+func (s *MemoryStore) recordPrices(games []SteamGame) {
+	now := s.Now()
+	for _, game := range games {
+		if game.AppID == 0 {
+			continue
+		}
+		key := historyKey(game.AppID, game.CountryCode)
+		points := s.history[key]
+		if n := len(points); n > 0 &&
+			points[n-1].PriceNow == game.PriceNow && points[n-1].Currency == game.Currency {
+			continue
+		}
+		s.history[key] = append(points, PricePoint{
+			PriceBefore: game.PriceBefore,
+			PriceNow:    game.PriceNow,
+			Discount:    game.Discount,
+			Currency:    game.Currency,
+			RecordedAt:  now,
+		})
+	}
+}
+
+func historyKey(appID int, cc string) string {
+	return fmt.Sprintf("%s:%d", cc, appID)
+}
+
+func copyGames(games []SteamGame) []SteamGame {
+	res := make([]SteamGame, len(games))
+	copy(res, games)
+	return res
+}
@@ -0,0 +1,63 @@
+package steam
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/this-is-a-bot/bot/logging"
+)
+
+// Called with the games of a crawl that weren't discounted in the previous
+// crawl of the region.
+type DiscountListener func(ctx context.Context, cc string, games []SteamGame)
+
+// Store telling a listener about new discounts as they are saved.
+type notifyingStore struct {
+	Store
+	onNew DiscountListener
+}
+
+// Wrap a store so onNew is called after saving discounts with the games
+// that are new since the previous save.
+func NewNotifyingStore(store Store, onNew DiscountListener) Store {
+	return &notifyingStore{Store: store, onNew: onNew}
+}
+
+func (s *notifyingStore) SaveDiscounts(ctx context.Context, cc string, games []SteamGame) error {
+	// Without the previous crawl every game would look new, so notifying is
+	// skipped if it can't be loaded or this is the first one.
+	previous, prevErr := s.Store.GetDiscounts(ctx, cc)
+	if prevErr != nil {
+		logging.Errorf(ctx, "failed to load previous steam discounts: %v", prevErr)
+	}
+
+	if err := s.Store.SaveDiscounts(ctx, cc, games); err != nil {
+		return err
+	}
+	if prevErr != nil || len(previous) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, game := range previous {
+		seen[gameKey(game)] = true
+	}
+	var added []SteamGame
+	for _, game := range games {
+		if !seen[gameKey(game)] {
+			added = append(added, game)
+		}
+	}
+	if len(added) > 0 {
+		s.onNew(ctx, normalizeCountryCode(cc), added)
+	}
+	return nil
+}
+
+// Identify a game across crawls, by app ID if known.
+func gameKey(game SteamGame) string {
+	if game.AppID != 0 {
+		return fmt.Sprintf("app:%d", game.AppID)
+	}
+	return game.URL
+}
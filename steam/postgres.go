@@ -0,0 +1,324 @@
+package steam
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+const discountTableName = "steam_discount_game"
+const featuredTableName = "steam_featured_game"
+const exchangeRateTableName = "steam_exchange_rate"
+const priceHistoryTableName = "steam_price_history"
+
+var (
+	queryAllDiscounts    string
+	queryAllFeatured     string
+	queryOneFeature      string
+	queryDiscountByAppID string
+	queryFeaturedByAppID string
+	queryPriceHistory    string
+	queryExchangeRates   string
+	insertPriceHistory   string
+	deleteDiscounts      string
+	insertDiscount       string
+	deleteFeatured       string
+	insertFeatured       string
+)
+
+// Prepare queries.
+func init() {
+	fields := []string{
+		"app_id", "name", "link", "img_src", "review", "price_before", "price_now",
+		"discount", "country_code", "currency"}
+	queryAllDiscounts = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE country_code = $1",
+		strings.Join(fields, ", "), discountTableName)
+
+	fieldsFeatured := []string{
+		"app_id", "name", "link", "img_src", "headline", "price_before", "price_now",
+		"discount", "country_code", "currency"}
+	queryAllFeatured = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE country_code = $1",
+		strings.Join(fieldsFeatured, ","), featuredTableName)
+	queryOneFeature = fmt.Sprintf("%s AND feature_type = $2", queryAllFeatured)
+
+	queryDiscountByAppID = fmt.Sprintf("%s AND app_id = $2", queryAllDiscounts)
+
+	queryFeaturedByAppID = fmt.Sprintf(
+		"SELECT feature_type, name, link, img_src FROM %s "+
+			"WHERE country_code = $1 AND app_id = $2 ORDER BY feature_type",
+		featuredTableName)
+
+	queryPriceHistory = fmt.Sprintf(
+		"SELECT price_before, price_now, discount, currency, recorded_at FROM %s "+
+			"WHERE app_id = $1 AND country_code = $2 ORDER BY recorded_at",
+		priceHistoryTableName)
+
+	queryExchangeRates = fmt.Sprintf(
+		"SELECT currency, rate FROM %s", exchangeRateTableName)
+
+	// Only record a new point when the price actually changed.
+	insertPriceHistory = fmt.Sprintf(
+		"INSERT INTO %[1]s (app_id, country_code, currency, price_before, price_now, discount) "+
+			"SELECT $1, $2, $3, $4, $5, $6 WHERE NOT EXISTS ("+
+			"SELECT 1 FROM (SELECT price_now, currency FROM %[1]s "+
+			"WHERE app_id = $1 AND country_code = $2 ORDER BY recorded_at DESC LIMIT 1) last "+
+			"WHERE last.price_now = $5 AND last.currency = $3)",
+		priceHistoryTableName)
+
+	deleteDiscounts = fmt.Sprintf(
+		"DELETE FROM %s WHERE country_code = $1", discountTableName)
+	insertDiscount = fmt.Sprintf(
+		"INSERT INTO %s (app_id, name, link, img_src, review, price_before, "+
+			"price_now, discount, country_code, currency) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		discountTableName)
+
+	deleteFeatured = fmt.Sprintf(
+		"DELETE FROM %s WHERE country_code = $1 AND feature_type = $2",
+		featuredTableName)
+	insertFeatured = fmt.Sprintf(
+		"INSERT INTO %s (feature_type, app_id, name, link, img_src, headline, "+
+			"price_before, price_now, discount, country_code, currency) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		featuredTableName)
+}
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// Create a store on top of the steam tables.
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) GetDiscounts(ctx context.Context, cc string) ([]SteamGame, error) {
+	rows, err := s.db.QueryContext(ctx, queryAllDiscounts, normalizeCountryCode(cc))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanGames(rows)
+}
+
+func (s *postgresStore) GetFeatured(ctx context.Context, feature string, cc string) ([]SteamGame, error) {
+	feature = normalizeFeature(feature)
+	if !IsValidFeature(feature) {
+		return nil, &InvalidFeatureError{feature}
+	}
+	rows, err := s.db.QueryContext(
+		ctx, queryOneFeature, normalizeCountryCode(cc), "featured_"+feature)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanGames(rows)
+}
+
+func scanGames(rows *sql.Rows) ([]SteamGame, error) {
+	res := make([]SteamGame, 0)
+	for rows.Next() {
+		var game SteamGame
+		var appID sql.NullInt64
+
+		err := rows.Scan(
+			&appID, &game.Name, &game.URL, &game.ImgSrc, &game.Review,
+			&game.PriceBefore, &game.PriceNow, &game.Discount, &game.CountryCode,
+			&game.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		// Rows written before app IDs were stored only have the link.
+		if appID.Valid {
+			game.AppID = int(appID.Int64)
+		} else {
+			game.AppID, _ = ParseAppID(game.URL)
+		}
+		res = append(res, game)
+	}
+	return res, rows.Err()
+}
+
+func (s *postgresStore) GetGame(ctx context.Context, appID int, cc string) (*GameDetail, error) {
+	cc = normalizeCountryCode(cc)
+	detail := newGameDetail(appID, cc)
+
+	rows, err := s.db.QueryContext(ctx, queryDiscountByAppID, cc, appID)
+	if err != nil {
+		return nil, err
+	}
+	discounts, err := scanGames(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(discounts) > 0 {
+		detail.setDiscount(discounts[0])
+	}
+
+	rows, err = s.db.QueryContext(ctx, queryFeaturedByAppID, cc, appID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var featureType, name, link, imgSrc string
+		if err = rows.Scan(&featureType, &name, &link, &imgSrc); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		detail.Featured = append(
+			detail.Featured, strings.TrimPrefix(featureType, "featured_"))
+		if detail.Name == "" {
+			detail.Name, detail.URL, detail.ImgSrc = name, link, imgSrc
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, queryPriceHistory, appID, cc)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var point PricePoint
+		err = rows.Scan(
+			&point.PriceBefore, &point.PriceNow, &point.Discount, &point.Currency,
+			&point.RecordedAt)
+		if err != nil {
+			return nil, err
+		}
+		detail.PriceHistory = append(detail.PriceHistory, point)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if detail.Name == "" && len(detail.PriceHistory) == 0 {
+		return nil, ErrGameNotFound
+	}
+	return detail, nil
+}
+
+func (s *postgresStore) GetExchangeRates(ctx context.Context) (ExchangeRates, error) {
+	rows, err := s.db.QueryContext(ctx, queryExchangeRates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make(ExchangeRates)
+	for rows.Next() {
+		var currency string
+		var rate float64
+		if err = rows.Scan(&currency, &rate); err != nil {
+			return nil, err
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	return rates, rows.Err()
+}
+
+func (s *postgresStore) SaveDiscounts(ctx context.Context, cc string, games []SteamGame) error {
+	cc = normalizeCountryCode(cc)
+	games = prepareForSave(games, cc)
+
+	err := s.inTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, deleteDiscounts, cc); err != nil {
+			return err
+		}
+		for _, game := range games {
+			_, err := tx.ExecContext(
+				ctx, insertDiscount, nullableAppID(game.AppID), game.Name, game.URL,
+				game.ImgSrc, game.Review, game.PriceBefore, game.PriceNow,
+				game.Discount, game.CountryCode, game.Currency)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.recordPrices(ctx, games)
+}
+
+func (s *postgresStore) SaveFeatured(ctx context.Context, feature string, cc string, games []SteamGame) error {
+	if !IsValidFeature(feature) {
+		return &InvalidFeatureError{feature}
+	}
+	cc = normalizeCountryCode(cc)
+	games = prepareForSave(games, cc)
+	featureType := "featured_" + feature
+
+	err := s.inTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, deleteFeatured, cc, featureType); err != nil {
+			return err
+		}
+		for _, game := range games {
+			// The featured table keeps the headline where discounts keep reviews.
+			_, err := tx.ExecContext(
+				ctx, insertFeatured, featureType, nullableAppID(game.AppID), game.Name,
+				game.URL, game.ImgSrc, game.Review, game.PriceBefore, game.PriceNow,
+				game.Discount, game.CountryCode, game.Currency)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.recordPrices(ctx, games)
+}
+
+// Record the current prices of games into the price history. Games without
+// an app ID are skipped, a point is only added when the price changed.
+func (s *postgresStore) recordPrices(ctx context.Context, games []SteamGame) error {
+	for _, game := range games {
+		if game.AppID == 0 {
+			continue
+		}
+		_, err := s.db.ExecContext(
+			ctx, insertPriceHistory, game.AppID, normalizeCountryCode(game.CountryCode),
+			game.Currency, game.PriceBefore, game.PriceNow, game.Discount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *postgresStore) inTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullableAppID(appID int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(appID), Valid: appID != 0}
+}
@@ -1,37 +1,29 @@
 package steam
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	_ "github.com/lib/pq"
+	"regexp"
 	"strings"
 )
 
-const discountTableName = "steam_discount_game"
-const featuredTableName = "steam_featured_game"
+// Country code used when the client doesn't ask for a particular region.
+const DefaultCountryCode = "us"
 
-var (
-	queryAllDiscounts string
-	queryAllFeatured  string
-)
+// Feature used when the client doesn't ask for a particular one.
+const DefaultFeature = "win"
 
-// Prepare queries.
-func init() {
-	fields := []string{
-		"name", "link", "img_src", "review", "price_before", "price_now",
-		"discount"}
-	queryAllDiscounts = fmt.Sprintf(
-		"SELECT %s FROM %s", strings.Join(fields, ", "), discountTableName)
-
-	fieldsFeatured := []string{
-		"name", "link", "img_src", "headline", "price_before", "price_now",
-		"discount"}
-	queryAllFeatured = fmt.Sprintf(
-		"SELECT %s FROM %s", strings.Join(fieldsFeatured, ","), featuredTableName)
+// Featured categories, stored in `feature_type` as "featured_<feature>".
+var Features = []string{
+	"win", "linux", "mac", "top_sellers", "new_releases", "coming_soon", "vr",
+	"free_to_play",
 }
 
+var countryCodePattern = regexp.MustCompile("^[a-z]{2}$")
+
 // Corresponds to rows in `steam_discount_game` table.
 type SteamGame struct {
+	AppID       int     `json:"appId"`
 	Name        string  `json:"name"`
 	URL         string  `json:"url"`
 	ImgSrc      string  `json:"imgSrc"`
@@ -39,71 +31,84 @@ type SteamGame struct {
 	PriceBefore float32 `json:"priceBefore"`
 	PriceNow    float32 `json:"priceNow"`
 	Discount    string  `json:"discount"`
+	CountryCode string  `json:"cc"`
+	Currency    string  `json:"currency"`
 }
 
-// Get all discounts in current discount table.
-func GetDiscounts(db *sql.DB) ([]SteamGame, error) {
-	rows, err := db.Query(queryAllDiscounts)
+// Store persists steam listings per country.
+type Store interface {
+	// Get all discounts in current discount table for the given country.
+	GetDiscounts(ctx context.Context, cc string) ([]SteamGame, error)
 
-	if err != nil {
-		return nil, err
-	}
+	// Get all featured games for the given country. An empty feature means
+	// DefaultFeature.
+	GetFeatured(ctx context.Context, feature string, cc string) ([]SteamGame, error)
 
-	defer rows.Close()
+	// Get the current discount, featured status, price history and review
+	// data of one game in the given country.
+	GetGame(ctx context.Context, appID int, cc string) (*GameDetail, error)
 
-	res := make([]SteamGame, 0)
-	for rows.Next() {
-		var game SteamGame
+	// Load the locally stored exchange rate table.
+	GetExchangeRates(ctx context.Context) (ExchangeRates, error)
 
-		err = rows.Scan(
-			&game.Name, &game.URL, &game.ImgSrc, &game.Review, &game.PriceBefore,
-			&game.PriceNow, &game.Discount)
-		if err != nil {
-			return nil, err
-		}
+	// Replace the discounted games of a country and record their prices.
+	SaveDiscounts(ctx context.Context, cc string, games []SteamGame) error
 
-		res = append(res, game)
-	}
-	return res, nil
+	// Replace the featured games of a country for one feature and record
+	// their prices.
+	SaveFeatured(ctx context.Context, feature string, cc string, games []SteamGame) error
 }
 
-// Get all featured games in current featured table.
-func GetFeatured(db *sql.DB, feature string) ([]SteamGame, error) {
-	if !IsValidFeature(feature) {
-		feature = "win"
-	}
-	queryOneFeature := fmt.Sprintf(
-		"%s WHERE feature_type='featured_%s'", queryAllFeatured, feature)
-	rows, err := db.Query(queryOneFeature)
+// Returned when asking for a feature not in Features.
+type InvalidFeatureError struct {
+	Feature string
+}
 
-	if err != nil {
-		return nil, err
+func (e *InvalidFeatureError) Error() string {
+	return fmt.Sprintf(
+		"unknown feature %q, valid features are: %s",
+		e.Feature, strings.Join(Features, ", "))
+}
+
+func IsValidFeature(feature string) bool {
+	for _, f := range Features {
+		if f == feature {
+			return true
+		}
 	}
+	return false
+}
 
-	defer rows.Close()
+func normalizeFeature(feature string) string {
+	if feature == "" {
+		return DefaultFeature
+	}
+	return feature
+}
 
-	res := make([]SteamGame, 0)
-	for rows.Next() {
-		var game SteamGame
+// Whether cc looks like an ISO 3166-1 alpha-2 country code, as used by the
+// Steam store's `cc` parameter. An empty string means the default region.
+func IsValidCountryCode(cc string) bool {
+	return cc == "" || countryCodePattern.MatchString(strings.ToLower(cc))
+}
 
-		err = rows.Scan(
-			&game.Name, &game.URL, &game.ImgSrc, &game.Review, &game.PriceBefore,
-			&game.PriceNow, &game.Discount)
-		if err != nil {
-			return nil, err
-		}
-		res = append(res, game)
+func normalizeCountryCode(cc string) string {
+	if cc == "" {
+		return DefaultCountryCode
 	}
-	return res, nil
+	return strings.ToLower(cc)
 }
 
-func IsValidFeature(feature string) bool {
-	switch feature {
-	case
-		"win",
-		"linux",
-		"mac":
-		return true
+// Fill in country, currency and app ID of games about to be stored.
+func prepareForSave(games []SteamGame, cc string) []SteamGame {
+	res := make([]SteamGame, len(games))
+	for i, game := range games {
+		game.CountryCode = cc
+		game.Currency = strings.ToUpper(game.Currency)
+		if game.AppID == 0 {
+			game.AppID, _ = ParseAppID(game.URL)
+		}
+		res[i] = game
 	}
-	return false
+	return res
 }
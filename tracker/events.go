@@ -0,0 +1,114 @@
+package tracker
+
+import (
+	"context"
+	"time"
+
+	"github.com/this-is-a-bot/bot/logging"
+)
+
+// Types of tracker events.
+const (
+	EventAdded   = "added"
+	EventMarked  = "marked"
+	EventUpdated = "updated"
+	EventRemoved = "removed"
+	EventTagged  = "tagged"
+)
+
+// A change to a catalog of a user.
+type Event struct {
+	Type      string    `json:"type"`
+	Username  string    `json:"username"`
+	App       string    `json:"app"`
+	CatalogID int       `json:"catalogId"`
+	Name      string    `json:"name,omitempty"`
+	Unit      string    `json:"unit,omitempty"`
+	Value     float64   `json:"value,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// Publisher sends tracker events to whoever listens.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Publishers publishes to every publisher in turn, returning the first error.
+type Publishers []Publisher
+
+func (pubs Publishers) Publish(ctx context.Context, event Event) error {
+	var first error
+	for _, pub := range pubs {
+		if err := pub.Publish(ctx, event); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Store publishing an event after every successful change.
+type publishingStore struct {
+	Store
+	pub Publisher
+}
+
+// Wrap a store so changes are published. Failing to publish is logged but
+// doesn't fail the change, listeners only miss the event.
+func NewPublishingStore(store Store, pub Publisher) Store {
+	return &publishingStore{Store: store, pub: pub}
+}
+
+func (s *publishingStore) publish(ctx context.Context, event Event) {
+	event.At = time.Now()
+	if err := s.pub.Publish(ctx, event); err != nil {
+		logging.Errorf(ctx, "failed to publish tracker event: %v", err)
+	}
+}
+
+func (s *publishingStore) MarkDone(ctx context.Context, username string, app string, catalogID int, value float64) error {
+	if err := s.Store.MarkDone(ctx, username, app, catalogID, value); err != nil {
+		return err
+	}
+	s.publish(ctx, Event{
+		Type: EventMarked, Username: username, App: app, CatalogID: catalogID, Value: value,
+	})
+	return nil
+}
+
+func (s *publishingStore) AddTracking(ctx context.Context, username string, app string, name string, unit string, tags []string, maxCatalogs int) (int64, error) {
+	id, err := s.Store.AddTracking(ctx, username, app, name, unit, tags, maxCatalogs)
+	if err != nil {
+		return 0, err
+	}
+	s.publish(ctx, Event{
+		Type: EventAdded, Username: username, App: app, CatalogID: int(id), Name: name, Unit: unit,
+		Tags: tags,
+	})
+	return id, nil
+}
+
+func (s *publishingStore) UpdateTracking(ctx context.Context, username string, app string, catalogID int, newName string, newUnit string, tags []string) error {
+	if err := s.Store.UpdateTracking(ctx, username, app, catalogID, newName, newUnit, tags); err != nil {
+		return err
+	}
+	s.publish(ctx, Event{
+		Type: EventUpdated, Username: username, App: app, CatalogID: catalogID, Name: newName, Unit: newUnit,
+	})
+	if tags != nil {
+		s.publish(ctx, Event{
+			Type: EventTagged, Username: username, App: app, CatalogID: catalogID, Tags: tags,
+		})
+	}
+	return nil
+}
+
+func (s *publishingStore) RemoveTracking(ctx context.Context, username string, app string, catalogID int) error {
+	if err := s.Store.RemoveTracking(ctx, username, app, catalogID); err != nil {
+		return err
+	}
+	s.publish(ctx, Event{
+		Type: EventRemoved, Username: username, App: app, CatalogID: catalogID,
+	})
+	return nil
+}
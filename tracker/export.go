@@ -0,0 +1,123 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+var (
+	queryUsers          string
+	queryCatalogsToDump string
+	queryEventsToDump   string
+)
+
+func init() {
+	queryUsers = fmt.Sprintf(
+		"SELECT c.app, c.username, count(DISTINCT c.id) FILTER (WHERE NOT c.disabled), "+
+			"max(e.marked_at) FROM %s c LEFT JOIN %s e ON e.catalog_id = c.id "+
+			"WHERE $1 = '' OR c.app = $1 GROUP BY c.app, c.username ORDER BY c.app, c.username",
+		trackerCatalogTableName, trackerEventTableName)
+
+	queryCatalogsToDump = fmt.Sprintf(
+		"SELECT id, app, name, unit, disabled, created_at FROM %s "+
+			"WHERE username = $1 AND ($2 = '' OR app = $2) ORDER BY id",
+		trackerCatalogTableName)
+
+	queryEventsToDump = fmt.Sprintf(
+		"SELECT e.catalog_id, e.value, e.marked_at FROM %s e JOIN %s c ON c.id = e.catalog_id "+
+			"WHERE c.username = $1 AND ($2 = '' OR c.app = $2) ORDER BY e.marked_at",
+		trackerEventTableName, trackerCatalogTableName)
+}
+
+// A user known to the tracker.
+type User struct {
+	App      string `json:"app"`
+	Username string `json:"username"`
+
+	// Active catalogs.
+	Catalogs int `json:"catalogs"`
+
+	// Latest event, nil if they never marked anything.
+	LastActive *time.Time `json:"lastActive"`
+}
+
+// Get every user who ever had a catalog, in one app or all of them if app
+// is empty.
+func GetUsers(ctx context.Context, db *sql.DB, app string) ([]User, error) {
+	rows, err := db.QueryContext(ctx, queryUsers, app)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]User, 0)
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.App, &user.Username, &user.Catalogs, &user.LastActive)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, user)
+	}
+	return res, rows.Err()
+}
+
+// A catalog with its whole history, removed ones included.
+type ExportedCatalog struct {
+	ID        int             `json:"id"`
+	App       string          `json:"app"`
+	Name      string          `json:"name"`
+	Unit      string          `json:"unit,omitempty"`
+	Removed   bool            `json:"removed"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Events    []ExportedEvent `json:"events"`
+}
+
+type ExportedEvent struct {
+	Value    float32   `json:"value"`
+	MarkedAt time.Time `json:"markedAt"`
+}
+
+// Get all catalogs and events of a user, in one app or all of them if app is
+// empty.
+func Export(ctx context.Context, db *sql.DB, username string, app string) ([]ExportedCatalog, error) {
+	rows, err := db.QueryContext(ctx, queryCatalogsToDump, username, app)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]ExportedCatalog, 0)
+	byID := make(map[int]int)
+	for rows.Next() {
+		c := ExportedCatalog{Events: []ExportedEvent{}}
+		err := rows.Scan(&c.ID, &c.App, &c.Name, &c.Unit, &c.Removed, &c.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byID[c.ID] = len(res)
+		res = append(res, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, queryEventsToDump, username, app)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var catalogID int
+		var e ExportedEvent
+		if err := rows.Scan(&catalogID, &e.Value, &e.MarkedAt); err != nil {
+			return nil, err
+		}
+		if i, ok := byID[catalogID]; ok {
+			res[i].Events = append(res[i].Events, e)
+		}
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,40 @@
+package tracker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ByID []Catalog
+
+func (a ByID) Len() int           { return len(a) }
+func (a ByID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByID) Less(i, j int) bool { return a[i].ID < a[j].ID }
+
+// Render catalogs as plain text, one line per catalog ordered by ID, e.g.
+// "1. Push-ups: 30 times", "2. Read: done" or "3. Run: x".
+func FormatListing(catalogs []Catalog) string {
+	sorted := make([]Catalog, len(catalogs))
+	copy(sorted, catalogs)
+	sort.Sort(ByID(sorted))
+
+	res := make([]string, 0)
+	for _, catalog := range sorted {
+		s := fmt.Sprintf("%d. %s", catalog.ID, catalog.Name)
+		if catalog.Done {
+			if catalog.Value > 0 {
+				s += fmt.Sprintf(": %v", catalog.Value)
+				if catalog.Unit != "" {
+					s += " " + catalog.Unit
+				}
+			} else {
+				s += ": done"
+			}
+		} else {
+			s += ": x"
+		}
+		res = append(res, s)
+	}
+	return strings.Join(res, "\n")
+}
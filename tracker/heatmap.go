@@ -0,0 +1,123 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Weeks covered by heatmaps by default and at most, a year like GitHub's.
+const (
+	DefaultHeatmapWeeks = 53
+	MaxHeatmapWeeks     = 53
+)
+
+// Heatmap levels, 0 for days without events.
+const heatmapLevels = 5
+
+// An event of a catalog, as read for history.
+type HistoryEvent struct {
+	CatalogID int       `json:"catalogId"`
+	Value     float64   `json:"value"`
+	MarkedAt  time.Time `json:"markedAt"`
+}
+
+// Events per day over whole weeks, GitHub contribution graph style. Days
+// run from a Sunday to today, in the timezone of the user's app.
+type Heatmap struct {
+	From  string       `json:"from"`
+	To    string       `json:"to"`
+	Total int          `json:"total"`
+	Max   int          `json:"max"`
+	Days  []HeatmapDay `json:"days"`
+}
+
+type HeatmapDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// Build the heatmap of the catalogs over the weeks ending with now's week.
+// Events of other catalogs are left out.
+func GetHeatmap(ctx context.Context, store Store, username string, app string, catalogs []Catalog, weeks int, now time.Time) (*Heatmap, error) {
+	loc := Timezone(ctx)
+	first := heatmapStart(now.In(loc), weeks)
+	y, m, d := first.Date()
+	events, err := store.GetHistory(ctx, username, app, time.Date(y, m, d, 0, 0, 0, 0, loc))
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[int]bool, len(catalogs))
+	for _, catalog := range catalogs {
+		wanted[catalog.ID] = true
+	}
+	counts := make(map[string]int)
+	for _, event := range events {
+		if wanted[event.CatalogID] {
+			counts[dayIn(event.MarkedAt, loc)]++
+		}
+	}
+	return newHeatmap(first, now.In(loc), counts), nil
+}
+
+// First day of a heatmap of the weeks ending with today's week, a Sunday.
+// Dates are kept in UTC so adding days isn't affected by DST.
+func heatmapStart(today time.Time, weeks int) time.Time {
+	y, m, d := today.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -int(day.Weekday())-7*(weeks-1))
+}
+
+func newHeatmap(first time.Time, today time.Time, counts map[string]int) *Heatmap {
+	y, m, d := today.Date()
+	last := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	res := &Heatmap{From: first.Format("2006-01-02"), To: last.Format("2006-01-02")}
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		count := counts[date]
+		res.Days = append(res.Days, HeatmapDay{Date: date, Count: count})
+		res.Total += count
+		if count > res.Max {
+			res.Max = count
+		}
+	}
+	return res
+}
+
+// Level of a count from 0 (no events) to 4 (the busiest days).
+func (h *Heatmap) level(count int) int {
+	if count <= 0 || h.Max == 0 {
+		return 0
+	}
+	level := (count*(heatmapLevels-1) + h.Max - 1) / h.Max
+	if level < 1 {
+		level = 1
+	}
+	return level
+}
+
+// Number of week columns.
+func (h *Heatmap) weeks() int {
+	return (len(h.Days) + 6) / 7
+}
+
+// Characters of each level in the text rendering.
+var heatmapRunes = []rune{'·', '░', '▒', '▓', '█'}
+
+// Render as Unicode blocks for chat, one line per weekday from Sunday under
+// a summary line, e.g. "12 events from 2017-05-07 to 2017-07-29".
+func (h *Heatmap) Text() string {
+	lines := make([]string, 0, 8)
+	lines = append(lines, fmt.Sprintf("%d events from %s to %s", h.Total, h.From, h.To))
+	for weekday := 0; weekday < 7; weekday++ {
+		var row []rune
+		for i := weekday; i < len(h.Days); i += 7 {
+			row = append(row, heatmapRunes[h.level(h.Days[i].Count)])
+		}
+		lines = append(lines, string(row))
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
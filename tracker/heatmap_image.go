@@ -0,0 +1,97 @@
+package tracker
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"time"
+)
+
+// Geometry of heatmap images, in pixels.
+const (
+	heatmapCell   = 10
+	heatmapStep   = 13
+	heatmapLeft   = 28
+	heatmapTop    = 16
+	heatmapMargin = 2
+)
+
+// Colors of each level, GitHub's greens.
+var heatmapColors = []color.RGBA{
+	{0xeb, 0xed, 0xf0, 0xff},
+	{0x9b, 0xe9, 0xa8, 0xff},
+	{0x40, 0xc4, 0x63, 0xff},
+	{0x30, 0xa1, 0x4e, 0xff},
+	{0x21, 0x6e, 0x39, 0xff},
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Render as an SVG image with month and weekday labels, each day has its
+// count as a tooltip.
+func (h *Heatmap) SVG() []byte {
+	width := heatmapLeft + h.weeks()*heatmapStep
+	height := heatmapTop + 7*heatmapStep
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" `+
+		`viewBox="0 0 %d %d" font-family="sans-serif" font-size="9" fill="#767676">`+"\n",
+		width, height, width, height)
+	for weekday := 1; weekday < 7; weekday += 2 {
+		fmt.Fprintf(buf, `<text x="0" y="%d">%s</text>`+"\n",
+			heatmapTop+weekday*heatmapStep+heatmapCell-1, time.Weekday(weekday).String()[:3])
+	}
+
+	// Label a month above the first week starting in it. The first week is
+	// only labelled if the next label won't overlap.
+	for week := 0; week < h.weeks(); week++ {
+		first, err := time.Parse("2006-01-02", h.Days[week*7].Date)
+		if err != nil {
+			continue
+		}
+		if week == 0 && first.Day() > 14 || week > 0 && first.Day() > 7 {
+			continue
+		}
+		fmt.Fprintf(buf, `<text x="%d" y="%d">%s</text>`+"\n",
+			heatmapLeft+week*heatmapStep, heatmapTop-6, first.Format("Jan"))
+	}
+
+	for i, day := range h.Days {
+		plural := "s"
+		if day.Count == 1 {
+			plural = ""
+		}
+		fmt.Fprintf(buf, `<rect x="%d" y="%d" width="%d" height="%d" rx="2" fill="%s">`+
+			`<title>%d event%s on %s</title></rect>`+"\n",
+			heatmapLeft+i/7*heatmapStep, heatmapTop+i%7*heatmapStep, heatmapCell, heatmapCell,
+			hexColor(heatmapColors[h.level(day.Count)]), day.Count, plural, day.Date)
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
+
+// Render as a PNG image of the cells alone, on white.
+func (h *Heatmap) PNG() ([]byte, error) {
+	width := 2*heatmapMargin + h.weeks()*heatmapStep - (heatmapStep - heatmapCell)
+	height := 2*heatmapMargin + 7*heatmapStep - (heatmapStep - heatmapCell)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.ZP, draw.Src)
+
+	for i, day := range h.Days {
+		x := heatmapMargin + i/7*heatmapStep
+		y := heatmapMargin + i%7*heatmapStep
+		fill := image.NewUniform(heatmapColors[h.level(day.Count)])
+		draw.Draw(img, image.Rect(x, y, x+heatmapCell, y+heatmapCell), fill, image.ZP, draw.Src)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
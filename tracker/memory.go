@@ -0,0 +1,181 @@
+package tracker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps catalogs in memory, for tests and local experiments.
+type MemoryStore struct {
+	// Clock used for new events and for deciding what's done today.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	nextID   int
+	catalogs map[int]*memoryCatalog
+}
+
+type memoryCatalog struct {
+	username string
+	app      string
+	name     string
+	unit     string
+	tags     []string
+	disabled bool
+	events   []memoryEvent
+}
+
+type memoryEvent struct {
+	value    float64
+	markedAt time.Time
+}
+
+// Create an empty in-memory store using the wall clock.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		Now:      time.Now,
+		nextID:   1,
+		catalogs: make(map[int]*memoryCatalog),
+	}
+}
+
+// Get the catalog if it's visible to the user, nil otherwise. Must hold mu.
+func (s *MemoryStore) lookup(username string, app string, catalogID int) *memoryCatalog {
+	c, ok := s.catalogs[catalogID]
+	if !ok || c.disabled || c.username != username || c.app != app {
+		return nil
+	}
+	return c
+}
+
+func (s *MemoryStore) GetTrackingCatalogs(ctx context.Context, username string, app string) ([]Catalog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now, loc := s.Now(), Timezone(ctx)
+	res := make([]Catalog, 0)
+	for id := range s.catalogs {
+		c := s.lookup(username, app, id)
+		if c == nil {
+			continue
+		}
+		catalog := Catalog{ID: id, Name: c.name, Unit: c.unit, Tags: append([]string{}, c.tags...)}
+		if n := len(c.events); n > 0 && doneOnDayIn(c.events[n-1].markedAt, now, loc) {
+			catalog.Done = true
+			catalog.Value = float32(c.events[n-1].value)
+		}
+		res = append(res, catalog)
+	}
+	sort.Sort(ByID(res))
+	return res, nil
+}
+
+func (s *MemoryStore) MarkDone(ctx context.Context, username string, app string, catalogID int, value float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.lookup(username, app, catalogID)
+	if c == nil {
+		return ErrCatalogNotFound
+	}
+	c.events = append(c.events, memoryEvent{value: value, markedAt: s.Now()})
+	return nil
+}
+
+func (s *MemoryStore) AddTracking(ctx context.Context, username string, app string, name string, unit string, tags []string, maxCatalogs int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxCatalogs > 0 {
+		n := 0
+		for id := range s.catalogs {
+			if s.lookup(username, app, id) != nil {
+				n++
+			}
+		}
+		if n >= maxCatalogs {
+			return 0, ErrTooManyCatalogs
+		}
+	}
+
+	id := s.nextID
+	s.nextID++
+	s.catalogs[id] = &memoryCatalog{
+		username: username, app: app, name: name, unit: unit, tags: append([]string{}, tags...),
+	}
+	return int64(id), nil
+}
+
+func (s *MemoryStore) UpdateTracking(ctx context.Context, username string, app string, catalogID int, newName string, newUnit string, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.lookup(username, app, catalogID)
+	if c == nil {
+		return ErrCatalogNotFound
+	}
+	c.name, c.unit = newName, newUnit
+	if tags != nil {
+		c.tags = append([]string{}, tags...)
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemoveTracking(ctx context.Context, username string, app string, catalogID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.lookup(username, app, catalogID)
+	if c == nil {
+		return ErrCatalogNotFound
+	}
+	c.disabled = true
+	return nil
+}
+
+func (s *MemoryStore) GetHistory(ctx context.Context, username string, app string, since time.Time) ([]HistoryEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]HistoryEvent, 0)
+	for id := range s.catalogs {
+		c := s.lookup(username, app, id)
+		if c == nil {
+			continue
+		}
+		for _, e := range c.events {
+			if !e.markedAt.Before(since) {
+				res = append(res, HistoryEvent{CatalogID: id, Value: e.value, MarkedAt: e.markedAt})
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].MarkedAt.Before(res[j].MarkedAt) })
+	return res, nil
+}
+
+func (s *MemoryStore) GetBrokenStreaks(ctx context.Context, now time.Time) ([]Streak, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]Streak, 0)
+	for id, c := range s.catalogs {
+		if c.disabled {
+			continue
+		}
+		markedAt := make([]time.Time, 0, len(c.events))
+		for _, e := range c.events {
+			markedAt = append(markedAt, e.markedAt)
+		}
+		days, lastDone := brokenStreakDays(markedAt, now, Timezone(ctx))
+		if days >= MinStreak {
+			res = append(res, Streak{
+				CatalogID: id, Username: c.username, App: c.app, Name: c.name,
+				Days: days, LastDone: lastDone,
+			})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CatalogID < res[j].CatalogID })
+	return res, nil
+}
@@ -0,0 +1,349 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const trackerCatalogTableName = "tracker_catalog"
+const trackerEventTableName = "tracker_events"
+const trackerTagTableName = "tracker_catalog_tag"
+const appTableName = "app"
+
+var (
+	queryTrackingListByUser              string
+	queryTrackingEventByID               string
+	queryActiveCatalogCount              string
+	lockUserCatalogs                     string
+	queryRecentEvents                    string
+	queryHistory                         string
+	insertTrackingCatalog                string
+	updateTrackingCatalog                string
+	disableTrackingCatalog               string
+	updateTrackingCatalogWithLatestEvent string
+	insertTrackingEvent                  string
+	lockTrackingCatalog                  string
+	deleteCatalogTags                    string
+	insertCatalogTags                    string
+)
+
+// Prepare queries.
+func init() {
+	// Every query is scoped through the app record, so nothing is visible
+	// in apps which aren't registered or are disabled.
+	activeApp := fmt.Sprintf(
+		"app IN (SELECT name FROM %s WHERE disabled_at IS NULL)", appTableName)
+
+	// Tags can't contain commas, they come back as one sorted list.
+	tags := fmt.Sprintf(
+		"(SELECT coalesce(string_agg(tag, ',' ORDER BY tag), '') FROM %s "+
+			"WHERE catalog_id = %s.id)",
+		trackerTagTableName, trackerCatalogTableName)
+
+	fields := []string{
+		"id", "name", "unit", "latest_event", tags,
+	}
+	queryTrackingListByUser = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE disabled IS FALSE AND username = $1 AND app = $2 AND %s",
+		strings.Join(fields, ","), trackerCatalogTableName, activeApp)
+
+	queryRecentEvents = fmt.Sprintf(
+		"SELECT c.id, c.username, c.app, c.name, a.timezone, e.marked_at FROM %s c "+
+			"JOIN %s a ON a.name = c.app AND a.disabled_at IS NULL "+
+			"JOIN %s e ON e.catalog_id = c.id "+
+			"WHERE c.disabled IS FALSE AND e.marked_at >= $1 ORDER BY c.id",
+		trackerCatalogTableName, appTableName, trackerEventTableName)
+
+	queryHistory = fmt.Sprintf(
+		"SELECT e.catalog_id, e.value, e.marked_at FROM %s e "+
+			"JOIN %s c ON c.id = e.catalog_id "+
+			"JOIN %s a ON a.name = c.app AND a.disabled_at IS NULL "+
+			"WHERE c.username = $1 AND c.app = $2 AND c.disabled IS FALSE AND e.marked_at >= $3 "+
+			"ORDER BY e.marked_at",
+		trackerEventTableName, trackerCatalogTableName, appTableName)
+
+	queryActiveCatalogCount = fmt.Sprintf(
+		"SELECT count(*) FROM %s WHERE username = $1 AND app = $2 AND disabled IS FALSE",
+		trackerCatalogTableName)
+
+	// Serializes adding catalogs of one user, so concurrent adds can't both
+	// pass the quota check. Held until the transaction ends.
+	lockUserCatalogs = "SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))"
+
+	insertTrackingCatalog = fmt.Sprintf(
+		"INSERT INTO %s (username, app, name, unit) SELECT $1, name, $3, $4 FROM %s "+
+			"WHERE name = $2 AND disabled_at IS NULL RETURNING id",
+		trackerCatalogTableName, appTableName)
+
+	updateTrackingCatalog = fmt.Sprintf(
+		"UPDATE %s SET name = $1, unit = $2 "+
+			"WHERE id = $3 AND username = $4 AND app = $5 AND disabled IS FALSE AND %s",
+		trackerCatalogTableName, activeApp)
+
+	disableTrackingCatalog = fmt.Sprintf(
+		"UPDATE %s SET disabled = TRUE "+
+			"WHERE id = $1 AND username = $2 AND app = $3 AND disabled IS FALSE AND %s",
+		trackerCatalogTableName, activeApp)
+
+	lockTrackingCatalog = fmt.Sprintf(
+		"SELECT id FROM %s "+
+			"WHERE id = $1 AND username = $2 AND app = $3 AND disabled IS FALSE AND %s "+
+			"FOR UPDATE",
+		trackerCatalogTableName, activeApp)
+
+	deleteCatalogTags = fmt.Sprintf(
+		"DELETE FROM %s WHERE catalog_id = $1", trackerTagTableName)
+
+	insertCatalogTags = fmt.Sprintf(
+		"INSERT INTO %s (catalog_id, tag) SELECT $1, unnest(string_to_array($2, ','))",
+		trackerTagTableName)
+
+	updateTrackingCatalogWithLatestEvent = fmt.Sprintf(
+		"UPDATE %s SET latest_event = $1 WHERE id = $2", trackerCatalogTableName)
+
+	queryTrackingEventByID = fmt.Sprintf(
+		"SELECT value, marked_at FROM %s WHERE id = $1", trackerEventTableName)
+
+	// Only insert for catalogs owned by the given user.
+	insertTrackingEvent = fmt.Sprintf(
+		"INSERT INTO %s (catalog_id, value) SELECT id, $2 FROM %s "+
+			"WHERE id = $1 AND username = $3 AND app = $4 AND disabled IS FALSE AND %s "+
+			"RETURNING id",
+		trackerEventTableName, trackerCatalogTableName, activeApp)
+}
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// Create a store on top of the `tracker_catalog` and `tracker_events` tables.
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) GetTrackingCatalogs(ctx context.Context, username string, app string) ([]Catalog, error) {
+	rows, err := s.db.QueryContext(ctx, queryTrackingListByUser, username, app)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now, loc := time.Now(), Timezone(ctx)
+	res := make([]Catalog, 0)
+	for rows.Next() {
+		var catalog Catalog
+		var latestEventID sql.NullInt64
+		var tags string
+
+		err = rows.Scan(&catalog.ID, &catalog.Name, &catalog.Unit, &latestEventID, &tags)
+		if err != nil {
+			return nil, err
+		}
+		catalog.Tags = make([]string, 0)
+		if tags != "" {
+			catalog.Tags = strings.Split(tags, ",")
+		}
+
+		// Fetch latest event to see whether this catalog has been completed.
+		if latestEventID.Valid {
+			var value float32
+			var markedAt time.Time
+			// TODO: join the table before to avoid this extra SQL query.
+			err = s.db.QueryRowContext(
+				ctx, queryTrackingEventByID, latestEventID.Int64).Scan(&value, &markedAt)
+			if err != nil {
+				return nil, err
+			}
+
+			if doneOnDayIn(markedAt, now, loc) {
+				// Already finished for today.
+				catalog.Done = true
+				catalog.Value = value
+			}
+		}
+
+		res = append(res, catalog)
+	}
+	return res, rows.Err()
+}
+
+func (s *postgresStore) MarkDone(ctx context.Context, username string, app string, catalogID int, value float64) error {
+	var eventID int64
+	err := s.db.QueryRowContext(
+		ctx, insertTrackingEvent, catalogID, value, username, app).Scan(&eventID)
+	if err == sql.ErrNoRows {
+		return ErrCatalogNotFound
+	} else if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, updateTrackingCatalogWithLatestEvent, eventID, catalogID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *postgresStore) AddTracking(ctx context.Context, username string, app string, name string, unit string, tags []string, maxCatalogs int) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, lockUserCatalogs, app, username); err != nil {
+		return 0, err
+	}
+	if maxCatalogs > 0 {
+		var n int
+		err = tx.QueryRowContext(ctx, queryActiveCatalogCount, username, app).Scan(&n)
+		if err != nil {
+			return 0, err
+		}
+		if n >= maxCatalogs {
+			return 0, ErrTooManyCatalogs
+		}
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, insertTrackingCatalog, username, app, name, unit).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrAppNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	if len(tags) > 0 {
+		_, err = tx.ExecContext(ctx, insertCatalogTags, id, strings.Join(tags, ","))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+func (s *postgresStore) UpdateTracking(ctx context.Context, username string, app string, catalogID int, newName string, newUnit string, tags []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, lockTrackingCatalog, catalogID, username, app).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrCatalogNotFound
+	} else if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(
+		ctx, updateTrackingCatalog, newName, newUnit, catalogID, username, app)
+	if err != nil {
+		return err
+	}
+	if err = expectOneRow(res); err != nil {
+		return err
+	}
+
+	if tags != nil {
+		if _, err = tx.ExecContext(ctx, deleteCatalogTags, catalogID); err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			_, err = tx.ExecContext(ctx, insertCatalogTags, catalogID, strings.Join(tags, ","))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *postgresStore) RemoveTracking(ctx context.Context, username string, app string, catalogID int) error {
+	res, err := s.db.ExecContext(ctx, disableTrackingCatalog, catalogID, username, app)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func (s *postgresStore) GetHistory(ctx context.Context, username string, app string, since time.Time) ([]HistoryEvent, error) {
+	rows, err := s.db.QueryContext(ctx, queryHistory, username, app, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]HistoryEvent, 0)
+	for rows.Next() {
+		var event HistoryEvent
+		if err := rows.Scan(&event.CatalogID, &event.Value, &event.MarkedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, event)
+	}
+	return res, rows.Err()
+}
+
+func (s *postgresStore) GetBrokenStreaks(ctx context.Context, now time.Time) ([]Streak, error) {
+	since := now.AddDate(0, 0, -streakWindow-2)
+	rows, err := s.db.QueryContext(ctx, queryRecentEvents, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]Streak, 0)
+	var current Streak
+	var loc *time.Location
+	var markedAt []time.Time
+	flush := func() {
+		if current.CatalogID == 0 {
+			return
+		}
+		current.Days, current.LastDone = brokenStreakDays(markedAt, now, loc)
+		if current.Days >= MinStreak {
+			res = append(res, current)
+		}
+	}
+	for rows.Next() {
+		var streak Streak
+		var tz string
+		var t time.Time
+		err = rows.Scan(&streak.CatalogID, &streak.Username, &streak.App, &streak.Name, &tz, &t)
+		if err != nil {
+			return nil, err
+		}
+		if streak.CatalogID != current.CatalogID {
+			flush()
+			current, markedAt = streak, nil
+			if loc = Timezone(ctx); tz != "" {
+				if appLoc, err := time.LoadLocation(tz); err == nil {
+					loc = appLoc
+				}
+			}
+		}
+		markedAt = append(markedAt, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return res, nil
+}
+
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCatalogNotFound
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/redis"
+)
+
+const eventChannelPrefix = "tracker:events:"
+
+// Events buffered per listener, a listener falling further behind misses
+// events rather than blocking the others.
+const listenerBuffer = 16
+
+// Hub fans tracker events out to listeners on every dyno: events are
+// published to Redis, and each dyno's hub keeps one subscription that
+// dispatches them to its local listeners.
+type Hub struct {
+	rs redis.RedisStore
+
+	mu        sync.Mutex
+	listeners map[listenerKey]map[chan Event]bool
+}
+
+// Listeners are keyed by the user whose events they receive.
+type listenerKey struct {
+	app      string
+	username string
+}
+
+// Create a hub, call Run to start receiving events.
+func NewHub(rs redis.RedisStore) *Hub {
+	return &Hub{rs: rs, listeners: make(map[listenerKey]map[chan Event]bool)}
+}
+
+// Redis channel of a user's events, e.g. "tracker:events:5:slack:alice".
+// Legacy app names may contain ":", so the app is length-prefixed.
+func eventChannel(username string, app string) string {
+	return fmt.Sprintf("%s%d:%s:%s", eventChannelPrefix, len(app), app, username)
+}
+
+// Publish an event to listeners of its user on all dynos.
+func (h *Hub) Publish(ctx context.Context, event Event) error {
+	js, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	conn := h.rs.GetConnection()
+	defer conn.Close()
+	_, err = conn.Do("PUBLISH", eventChannel(event.Username, event.App), js)
+	return err
+}
+
+// Listen to events of a user. Call stop once done.
+func (h *Hub) Listen(username string, app string) (events <-chan Event, stop func()) {
+	key := listenerKey{app: app, username: username}
+	ch := make(chan Event, listenerBuffer)
+
+	h.mu.Lock()
+	if h.listeners[key] == nil {
+		h.listeners[key] = make(map[chan Event]bool)
+	}
+	h.listeners[key][ch] = true
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.listeners[key], ch)
+			if len(h.listeners[key]) == 0 {
+				delete(h.listeners, key)
+			}
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Dispatch an event to the local listeners of its user.
+func (h *Hub) dispatch(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.listeners[listenerKey{app: event.App, username: event.Username}] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Receive events from Redis until ctx is done, reconnecting with backoff
+// if the subscription breaks.
+func (h *Hub) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := h.receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		logging.Errorf(ctx, "tracker event subscription failed, retrying in %v: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// Hold one pattern subscription to all users' events.
+func (h *Hub) receive(ctx context.Context) error {
+	psc, err := h.rs.PubSub()
+	if err != nil {
+		return err
+	}
+	if err = psc.PSubscribe(eventChannelPrefix + "*"); err != nil {
+		psc.Close()
+		return err
+	}
+
+	// Closing the connection unblocks Receive. Pings keep it from being
+	// dropped as idle.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				psc.Close()
+				return
+			case <-done:
+				psc.Close()
+				return
+			case <-ticker.C:
+				psc.Ping("")
+			}
+		}
+	}()
+
+	for {
+		switch msg := psc.Receive().(type) {
+		case redigo.PMessage:
+			var event Event
+			if err := json.Unmarshal(msg.Data, &event); err != nil {
+				logging.Errorf(ctx, "malformed tracker event on %s: %v", msg.Channel, err)
+				continue
+			}
+			h.dispatch(event)
+		case error:
+			return msg
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package tracker
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Most tags a catalog can have.
+const MaxTags = 20
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Lowercase, sort and dedupe tags, checking each is a valid tag name.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: use up to 32 lowercase letters, digits, '-' or '_'", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			res = append(res, tag)
+		}
+	}
+	if len(res) > MaxTags {
+		return nil, fmt.Errorf("a catalog has at most %d tags", MaxTags)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// Whether the catalog has every one of the tags.
+func (c Catalog) HasTags(tags ...string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range c.Tags {
+			if strings.EqualFold(t, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Catalogs having every one of the tags, all of them without tags.
+func FilterByTags(catalogs []Catalog, tags ...string) []Catalog {
+	if len(tags) == 0 {
+		return catalogs
+	}
+	res := make([]Catalog, 0)
+	for _, catalog := range catalogs {
+		if catalog.HasTags(tags...) {
+			res = append(res, catalog)
+		}
+	}
+	return res
+}
+
+// Today's progress over a set of catalogs, e.g. all of a user's "fitness"
+// habits.
+type Summary struct {
+	Tags    []string  `json:"tags"`
+	Total   int       `json:"total"`
+	Done    int       `json:"done"`
+	AllDone bool      `json:"allDone"`
+	Pending []Catalog `json:"pending"`
+}
+
+// Summarize today's status of the catalogs having every one of the tags.
+// Nothing left to do counts as all done, even without catalogs.
+func Summarize(catalogs []Catalog, tags ...string) Summary {
+	res := Summary{Tags: tags, Pending: make([]Catalog, 0)}
+	if res.Tags == nil {
+		res.Tags = make([]string, 0)
+	}
+	for _, catalog := range FilterByTags(catalogs, tags...) {
+		res.Total++
+		if catalog.Done {
+			res.Done++
+		} else {
+			res.Pending = append(res.Pending, catalog)
+		}
+	}
+	sort.Sort(ByID(res.Pending))
+	res.AllDone = res.Done == res.Total
+	return res
+}
+
+// Render a summary as plain text, e.g. "fitness: 2/3 done, left: 3. Run".
+func FormatSummary(summary Summary) string {
+	s := fmt.Sprintf("%d/%d done", summary.Done, summary.Total)
+	if len(summary.Tags) > 0 {
+		s = strings.Join(summary.Tags, ", ") + ": " + s
+	}
+	if summary.AllDone {
+		return s + ", all done for today"
+	}
+	left := make([]string, 0, len(summary.Pending))
+	for _, catalog := range summary.Pending {
+		left = append(left, fmt.Sprintf("%d. %s", catalog.ID, catalog.Name))
+	}
+	return s + ", left: " + strings.Join(left, ", ")
+}
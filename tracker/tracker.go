@@ -1,56 +1,60 @@
 package tracker
 
 import (
-	"database/sql"
+	"context"
 	"errors"
-	"fmt"
-	"strings"
 	"time"
 )
 
-const trackerCatalogTableName = "tracker_catalog"
-const trackerEventTableName = "tracker_events"
-
 var (
-	queryTrackingListByUser              string
-	queryTrackingEventByID               string
-	insertTrackingCatalog                string
-	updateTrackingCatalogWithLatestEvent string
-	insertTrackingEvent                  string
-
-	// TODO: Hardcoded timezone for now (Pacific time).
-	pacific *time.Location
+	// Returned when a catalog doesn't exist or belongs to someone else.
+	ErrCatalogNotFound = errors.New("catalog not found")
+
+	// Returned when adding a catalog to an app that isn't registered or is
+	// disabled.
+	ErrAppNotFound = errors.New("app not found")
+
+	// Returned when adding a catalog would exceed the user's quota.
+	ErrTooManyCatalogs = errors.New("too many catalogs")
+
+	// Timezone deciding when a day starts, Pacific time unless configured.
+	location *time.Location
 )
 
-// Prepare queries.
 func init() {
-	fields := []string{
-		"id", "name", "unit", "latest_event",
+	// Load timezone as Pacific time.
+	if err := SetTimezone("US/Pacific"); err != nil {
+		panic(err)
 	}
-	queryTrackingListByUser = fmt.Sprintf(
-		"SELECT %s FROM %s WHERE disabled IS FALSE AND username = $1 AND app = $2",
-		strings.Join(fields, ","), trackerCatalogTableName)
-
-	insertTrackingCatalog = fmt.Sprintf(
-		"INSERT INTO %s (USERNAME, APP, NAME, UNIT) VALUES ($1, $2, $3, $4) RETURNING id",
-		trackerCatalogTableName)
+}
 
-	updateTrackingCatalogWithLatestEvent = fmt.Sprintf(
-		"UPDATE %s SET latest_event = $1 WHERE id = $2", trackerCatalogTableName)
+// Set the timezone deciding when a tracking day starts.
+func SetTimezone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+	location = loc
+	return nil
+}
 
-	queryTrackingEventByID = fmt.Sprintf(
-		"SELECT value, marked_at FROM %s WHERE id = $1", trackerEventTableName)
+type timezoneKey struct{}
 
-	insertTrackingEvent = fmt.Sprintf(
-		"INSERT INTO %s (catalog_id, value) VALUES ($1, $2) RETURNING id",
-		trackerEventTableName)
+// Use another timezone than the configured one for calls with the returned
+// context, e.g. the one of the caller's app.
+func WithTimezone(ctx context.Context, loc *time.Location) context.Context {
+	if loc == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, timezoneKey{}, loc)
+}
 
-	// Load timezone as Pacific time.
-	var err error
-	pacific, err = time.LoadLocation("US/Pacific")
-	if err != nil {
-		panic(err)
+// Timezone deciding when a day starts for calls with ctx.
+func Timezone(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(timezoneKey{}).(*time.Location); ok {
+		return loc
 	}
+	return location
 }
 
 type Catalog struct {
@@ -59,87 +63,94 @@ type Catalog struct {
 	Unit  string  `json:"unit,omitempty"`
 	Done  bool    `json:"done"`
 	Value float32 `json:"value,omitempty"`
+
+	// Tags grouping catalogs across the user's list, sorted.
+	Tags []string `json:"tags"`
 }
 
-// Get a list of tracking catalogs, specifying the current status for each one.
-func GetTrackingCatalogs(db *sql.DB, username string, app string) ([]Catalog, error) {
-	rows, err := db.Query(queryTrackingListByUser, username, app)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	res := make([]Catalog, 0)
-	for rows.Next() {
-		var catalog Catalog
-		var latestEventID sql.NullInt64
-
-		err = rows.Scan(&catalog.ID, &catalog.Name, &catalog.Unit, &latestEventID)
-		if err != nil {
-			return nil, err
-		}
-
-		// Fetch latest event to see whether this catalog has been completed.
-		if latestEventID.Valid {
-			var value float32
-			var markedAt time.Time
-			// TODO: join the table before to avoid this extra SQL query.
-			err = db.QueryRow(
-				queryTrackingEventByID, latestEventID.Int64).Scan(&value, &markedAt)
-			if err != nil {
-				return nil, err
-			}
-
-			// Convert to pacific time.
-			now := time.Now().In(pacific)
-			markedAt = markedAt.In(pacific)
-			y1, m1, d1 := now.Date()
-			y2, m2, d2 := markedAt.Date()
-			if y1 == y2 && m1 == m2 && d1 == d2 {
-				// Already finished for today.
-				catalog.Done = true
-				catalog.Value = value
-			}
-		}
-
-		res = append(res, catalog)
-	}
-	return res, nil
+// Store persists tracking catalogs and their events. Every call is scoped to
+// one user of one app.
+type Store interface {
+	// Get a list of tracking catalogs, specifying the current status for each one.
+	GetTrackingCatalogs(ctx context.Context, username string, app string) ([]Catalog, error)
+
+	// Mark done for a given catalog (add an event to the catalog with timestamp).
+	MarkDone(ctx context.Context, username string, app string, catalogID int, value float64) error
+
+	// Add a tracking item with its tags, returning its ID. The user keeps at
+	// most maxCatalogs active ones unless it's 0. Tags must be normalized.
+	AddTracking(ctx context.Context, username string, app string, name string, unit string, tags []string, maxCatalogs int) (int64, error)
+
+	// Modify the tracking catalog with new name / unit, and replace its tags
+	// unless they're nil. Tags must be normalized.
+	UpdateTracking(ctx context.Context, username string, app string, catalogID int, newName string, newUnit string, tags []string) error
+
+	// Delete the tracking item, its history is kept.
+	RemoveTracking(ctx context.Context, username string, app string, catalogID int) error
+
+	// Get events of the user's active catalogs marked since a time, oldest
+	// first.
+	GetHistory(ctx context.Context, username string, app string, since time.Time) ([]HistoryEvent, error)
+
+	// Get streaks of all users broken yesterday, i.e. catalogs done at least
+	// MinStreak days in a row until the day before yesterday, but not
+	// yesterday.
+	GetBrokenStreaks(ctx context.Context, now time.Time) ([]Streak, error)
 }
 
-// Mark done for a given catalog (add an event to the catalog with timestamp).
-func MarkDone(db *sql.DB, catalogID int, value float64) error {
-	var eventID int64
-	err := db.QueryRow(insertTrackingEvent, catalogID, value).Scan(&eventID)
-	if err != nil {
-		return err
-	}
+// Shortest run of days worth reporting as a broken streak.
+const MinStreak = 2
 
-	_, err = db.Exec(updateTrackingCatalogWithLatestEvent, eventID, catalogID)
-	if err != nil {
-		return err
-	}
+// Streaks are only counted this far back.
+const streakWindow = 60
 
-	return nil
+// A run of consecutive days a catalog was done.
+type Streak struct {
+	CatalogID int       `json:"catalogId"`
+	Username  string    `json:"username"`
+	App       string    `json:"app"`
+	Name      string    `json:"name"`
+	Days      int       `json:"days"`
+	LastDone  time.Time `json:"lastDone"`
 }
 
-// Add a tracking item for the particular user.
-func AddTracking(db *sql.DB, username string, app string, name string, unit string) (int64, error) {
-	var id int64
-	err := db.QueryRow(insertTrackingCatalog, username, app, name, unit).Scan(&id)
-	if err != nil {
-		return 0, err
+// Day of t in the configured timezone, e.g. "2017-05-01".
+func Day(t time.Time) string {
+	return dayIn(t, location)
+}
+
+func dayIn(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
+}
+
+// Length of the streak ending the day before yesterday given the times a
+// catalog was marked, 0 if it was also done yesterday.
+func brokenStreakDays(markedAt []time.Time, now time.Time, loc *time.Location) (int, time.Time) {
+	days := make(map[string]bool)
+	for _, t := range markedAt {
+		days[dayIn(t, loc)] = true
 	}
 
-	return id, nil
+	y, m, d := now.In(loc).Date()
+	yesterday := time.Date(y, m, d-1, 0, 0, 0, 0, loc)
+	if days[dayIn(yesterday, loc)] {
+		return 0, time.Time{}
+	}
+	n, lastDone := 0, yesterday.AddDate(0, 0, -1)
+	for day := lastDone; days[dayIn(day, loc)] && n < streakWindow; day = day.AddDate(0, 0, -1) {
+		n++
+	}
+	return n, lastDone
 }
 
-// Modify the tracking catalog with new name / unit.
-func UpdateTracking(db *sql.DB, catalogID int, newName string, newUnit string) (int, error) {
-	return 0, errors.New("not implemented")
+// Whether an event marked at markedAt counts as done on the day of now, in
+// the configured timezone.
+func DoneOnDay(markedAt time.Time, now time.Time) bool {
+	return doneOnDayIn(markedAt, now, location)
 }
 
-// Delete the tracking item.
-func RemoveTracking(db *sql.DB, catalogID int) error {
-	return errors.New("not implemented")
+func doneOnDayIn(markedAt time.Time, now time.Time, loc *time.Location) bool {
+	y1, m1, d1 := now.In(loc).Date()
+	y2, m2, d2 := markedAt.In(loc).Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
 }
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Networks webhooks may not point to: loopback, link-local (cloud metadata
+// lives there), private and shared (CGNAT) ranges, "this host", protocol
+// assignments, benchmarking, multicast and reserved ranges, and NAT64 which
+// reaches IPv4 addresses through a gateway. Delivery results are shown to
+// the app, so reaching them would let any app probe our network.
+var internalNetworks []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+		"172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15",
+		"224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::1/128", "64:ff9b::/96", "fc00::/7", "fe80::/10", "ff00::/8",
+	} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		internalNetworks = append(internalNetworks, network)
+	}
+}
+
+// Whether ip is in one of the internal networks, or isn't a global unicast
+// address at all.
+func isInternal(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	if !ip.IsGlobalUnicast() {
+		return true
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dialer hook refusing internal addresses. It runs on the resolved address
+// of every connection, so a hostname re-resolving to an internal address
+// after registration is refused too.
+func refuseInternal(network string, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isInternal(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
+
+// Client posting with the given dialer. Redirects aren't followed, they
+// would lead the request past the webhook URL the app registered; the 3xx
+// counts as a failed attempt.
+func newClient(dialer *net.Dialer) *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
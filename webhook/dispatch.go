@@ -0,0 +1,301 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/queue"
+)
+
+// Type of the queue jobs sending one delivery.
+const DeliverJob = "webhook.deliver"
+
+const (
+	// Deliveries are given up after this many attempts.
+	maxAttempts = 10
+
+	// Delay before the first retry, doubled for every further one.
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+
+	// Pending deliveries this much overdue are put back in the queue if their
+	// job is gone, e.g. because enqueueing failed.
+	requeueAfter = 5 * time.Minute
+
+	// How long a claimed delivery is kept from other jobs while it's sent.
+	claimFor = time.Minute
+)
+
+var (
+	insertDelivery        string
+	claimDelivery         string
+	updateDeliveryOutcome string
+	updateDeliveryJob     string
+	queryOverdue          string
+)
+
+func init() {
+	insertDelivery = fmt.Sprintf(
+		"INSERT INTO %s (webhook_id, event, payload) VALUES ($1, $2, $3) RETURNING id",
+		deliveryTableName)
+
+	// Counting the attempt and pushing next_attempt_at past the send makes
+	// the claim exclusive: other jobs of the same delivery find nothing due.
+	// A few seconds of slack cover clocks of workers running a bit ahead.
+	claimDelivery = fmt.Sprintf(
+		"UPDATE %s d SET attempts = d.attempts + 1, next_attempt_at = now() + $2 * interval '1 second' "+
+			"FROM %s w WHERE w.id = d.webhook_id AND d.id = $1 AND d.status = 'pending' "+
+			"AND d.next_attempt_at <= now() + interval '5 seconds' "+
+			"RETURNING d.event, d.payload, d.attempts, w.url, w.secret, w.disabled",
+		deliveryTableName, webhookTableName)
+
+	updateDeliveryOutcome = fmt.Sprintf(
+		"UPDATE %s SET status = $2, response_status = $3, last_error = $4, "+
+			"next_attempt_at = $5, delivered_at = CASE WHEN $2 = 'delivered' THEN now() END "+
+			"WHERE id = $1",
+		deliveryTableName)
+
+	updateDeliveryJob = fmt.Sprintf(
+		"UPDATE %s SET job_id = $2 WHERE id = $1", deliveryTableName)
+
+	queryOverdue = fmt.Sprintf(
+		"SELECT id, job_id FROM %s WHERE status = 'pending' AND next_attempt_at < $1",
+		deliveryTableName)
+}
+
+// Body posted to webhooks.
+type Payload struct {
+	Event      string      `json:"event"`
+	App        string      `json:"app,omitempty"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+// Queue job payload of DeliverJob.
+type deliverJob struct {
+	DeliveryID int64 `json:"deliveryId"`
+}
+
+// Dispatcher records deliveries in Postgres and schedules them as queue
+// jobs, sent by workers handling DeliverJob with Deliver.
+type Dispatcher struct {
+	db     *sql.DB
+	jobs   *queue.Queue
+	client *http.Client
+}
+
+// Create a dispatcher scheduling deliveries on the given queue.
+func NewDispatcher(db *sql.DB, jobs *queue.Queue) *Dispatcher {
+	client := newClient(&net.Dialer{Timeout: 5 * time.Second, Control: refuseInternal})
+	return &Dispatcher{db: db, jobs: jobs, client: client}
+}
+
+// Queue an event for the app's webhooks subscribed to it. An empty app
+// sends it to subscribed webhooks of every app, e.g. for steam events.
+func (d *Dispatcher) Emit(ctx context.Context, app string, event string, data interface{}) error {
+	js, err := json.Marshal(Payload{Event: event, App: app, OccurredAt: time.Now(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	rows, err := d.db.QueryContext(ctx, queryWebhooksByEvent, app, event)
+	if err != nil {
+		return err
+	}
+	var webhookIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		webhookIDs = append(webhookIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, webhookID := range webhookIDs {
+		var deliveryID int64
+		err := d.db.QueryRowContext(ctx, insertDelivery, webhookID, event, string(js)).Scan(&deliveryID)
+		if err != nil {
+			return err
+		}
+		// Recorded already, the sweep picks it up if this fails.
+		if err := d.enqueue(ctx, deliveryID, time.Time{}); err != nil {
+			logging.Errorf(ctx, "failed to enqueue webhook delivery %d: %v", deliveryID, err)
+		}
+	}
+	return nil
+}
+
+// Send the delivery of a DeliverJob. Failed sends are retried with their own
+// backoff, only failing to reach the database fails the job.
+func (d *Dispatcher) Deliver(ctx context.Context, job *queue.Job) error {
+	var payload deliverJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	return d.deliver(ctx, payload.DeliveryID)
+}
+
+// Put pending deliveries that should have been sent long ago and have no
+// job waiting or running back in the queue. Meant to run every minute or
+// so.
+func (d *Dispatcher) RequeueOverdue(ctx context.Context) error {
+	rows, err := d.db.QueryContext(ctx, queryOverdue, time.Now().Add(-requeueAfter))
+	if err != nil {
+		return err
+	}
+	type overdue struct {
+		id    int64
+		jobID string
+	}
+	var deliveries []overdue
+	for rows.Next() {
+		var o overdue
+		if err := rows.Scan(&o.id, &o.jobID); err != nil {
+			rows.Close()
+			return err
+		}
+		deliveries = append(deliveries, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, o := range deliveries {
+		if o.jobID != "" {
+			// Just behind, e.g. while workers catch up on a backlog.
+			if live, err := d.jobs.Live(ctx, o.jobID); err != nil {
+				lastErr = err
+				continue
+			} else if live {
+				continue
+			}
+		}
+		if err := d.enqueue(ctx, o.id, time.Time{}); err != nil {
+			logging.Errorf(ctx, "failed to requeue webhook delivery %d: %v", o.id, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Queue a job sending the delivery at the given time, or as soon as
+// possible if it's zero, and remember it on the delivery.
+func (d *Dispatcher) enqueue(ctx context.Context, id int64, at time.Time) error {
+	jobID, err := d.jobs.EnqueueAt(ctx, DeliverJob, deliverJob{id}, at)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.ExecContext(ctx, updateDeliveryJob, id, jobID)
+	return err
+}
+
+// Attempt one delivery, recording the outcome and scheduling a retry if it
+// failed. Only the job that claims the delivery sends it, extra jobs of the
+// same delivery do nothing.
+func (d *Dispatcher) deliver(ctx context.Context, id int64) error {
+	var event, payload, url, secret string
+	var attempts int
+	var disabled bool
+	err := d.db.QueryRowContext(ctx, claimDelivery, id, int(claimFor/time.Second)).Scan(
+		&event, &payload, &attempts, &url, &secret, &disabled)
+	if err == sql.ErrNoRows {
+		// Already delivered, given up, or claimed by another job.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if disabled {
+		return d.record(ctx, id, StatusFailed, 0, "webhook was removed", time.Now())
+	}
+
+	status, sendErr := d.send(ctx, id, event, url, secret, []byte(payload))
+	if sendErr == nil {
+		return d.record(ctx, id, StatusDelivered, status, "", time.Now())
+	}
+	if attempts >= maxAttempts {
+		return d.record(ctx, id, StatusFailed, status, sendErr.Error(), time.Now())
+	}
+
+	next := time.Now().Add(Backoff(attempts))
+	if err := d.record(ctx, id, StatusPending, status, sendErr.Error(), next); err != nil {
+		return err
+	}
+	return d.enqueue(ctx, id, next)
+}
+
+func (d *Dispatcher) record(ctx context.Context, id int64, status string, responseStatus int, lastError string, next time.Time) error {
+	_, err := d.db.ExecContext(
+		ctx, updateDeliveryOutcome, id, status, responseStatus, lastError, next)
+	return err
+}
+
+// Post a signed payload, returning the response status. Anything but 2xx
+// is an error.
+func (d *Dispatcher) send(ctx context.Context, id int64, event string, url string, secret string, payload []byte) (int, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bot-webhooks/1")
+	req.Header.Set("X-Bot-Event", event)
+	req.Header.Set("X-Bot-Delivery", strconv.FormatInt(id, 10))
+	req.Header.Set("X-Bot-Signature", Sign(secret, time.Now(), payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// Delay before retrying after the given number of failed attempts.
+func Backoff(attempts int) time.Duration {
+	backoff := baseBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// Signature header of a payload: "t=<unix time>,v1=<hex HMAC-SHA256 of
+// "<unix time>.<payload>" keyed with the webhook secret>". Receivers should
+// recompute it and reject old timestamps to prevent replays.
+func Sign(secret string, at time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
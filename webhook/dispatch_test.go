@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	at := time.Unix(1500000000, 0)
+	payload := []byte(`{"event":"catalog.created"}`)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1500000000." + string(payload)))
+	want := "t=1500000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("whsec_test", at, payload); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+	if Sign("whsec_other", at, payload) == want {
+		t.Error("signature doesn't depend on the secret")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, 512 * 30 * time.Second},
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+	for _, test := range tests {
+		if got := Backoff(test.attempts); got != test.want {
+			t.Errorf("Backoff(%d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
+
+// Dispatcher allowed to reach the local test receivers.
+func localDispatcher() *Dispatcher {
+	return &Dispatcher{client: newClient(&net.Dialer{})}
+}
+
+func TestSend(t *testing.T) {
+	payload := []byte(`{"event":"catalog.created"}`)
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	status, err := localDispatcher().send(
+		context.Background(), 42, "catalog.created", receiver.URL, "whsec_test", payload)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("send = %d, %v, want 204, nil", status, err)
+	}
+	if string(body) != string(payload) {
+		t.Errorf("body = %s, want %s", body, payload)
+	}
+	if event := got.Header.Get("X-Bot-Event"); event != "catalog.created" {
+		t.Errorf("X-Bot-Event = %q", event)
+	}
+	if id := got.Header.Get("X-Bot-Delivery"); id != "42" {
+		t.Errorf("X-Bot-Delivery = %q", id)
+	}
+
+	signature := got.Header.Get("X-Bot-Signature")
+	var timestamp int64
+	if i := strings.Index(signature, ","); i < 0 || !strings.HasPrefix(signature, "t=") {
+		t.Fatalf("malformed signature %q", signature)
+	} else if timestamp, err = strconv.ParseInt(signature[2:i], 10, 64); err != nil {
+		t.Fatalf("malformed signature %q", signature)
+	}
+	if want := Sign("whsec_test", time.Unix(timestamp, 0), payload); signature != want {
+		t.Errorf("X-Bot-Signature = %q, want %q", signature, want)
+	}
+}
+
+func TestSendFailures(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/" && r.Method == "GET" {
+			t.Error("redirect was followed")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	for path, want := range map[string]int{"/": 500, "/redirect": 302} {
+		status, err := localDispatcher().send(
+			context.Background(), 1, "catalog.created", receiver.URL+path, "whsec_test", []byte("{}"))
+		if err == nil || status != want {
+			t.Errorf("send to %s = %d, %v, want %d and an error", path, status, err, want)
+		}
+	}
+}
+
+func TestSendRefusesInternal(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("internal address was reached")
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(nil, nil)
+	_, err := d.send(context.Background(), 1, "catalog.created", receiver.URL, "whsec_test", []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("send to %s = %v, want refused", receiver.URL, err)
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/hook":         true,
+		"http://93.184.216.34/hook":        true,
+		"ftp://example.com/hook":           false,
+		"http://localhost:8080/hook":       false,
+		"http://127.0.0.1/hook":            false,
+		"http://169.254.169.254/latest":    false,
+		"http://10.1.2.3/hook":             false,
+		"http://192.168.0.10/hook":         false,
+		"http://[::1]/hook":                false,
+		"http://[::ffff:172.16.0.1]/hook":  false,
+		"https://api.localhost/hook":       false,
+		"http://0.0.0.0/hook":              false,
+		"http://100.64.0.1/hook":           false,
+		"http://192.0.0.170/hook":          false,
+		"http://198.18.0.1/hook":           false,
+		"http://224.0.0.1/hook":            false,
+		"http://255.255.255.255/hook":      false,
+		"http://[64:ff9b::a9fe:a9fe]/hook": false,
+		"http://[ff02::1]/hook":            false,
+	}
+	for url, valid := range tests {
+		wh := Webhook{URL: url, Events: []string{EventCatalogCreated}}
+		if err := wh.Validate(); (err == nil) != valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", url, err, valid)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/this-is-a-bot/bot/logging"
+	"github.com/this-is-a-bot/bot/steam"
+	"github.com/this-is-a-bot/bot/tracker"
+)
+
+// Publisher of tracker events to webhooks, to wrap a tracker store with.
+func (d *Dispatcher) TrackerPublisher() tracker.Publisher {
+	return trackerPublisher{d}
+}
+
+type trackerPublisher struct {
+	d *Dispatcher
+}
+
+func (p trackerPublisher) Publish(ctx context.Context, e tracker.Event) error {
+	switch e.Type {
+	case tracker.EventAdded:
+		return p.d.Emit(ctx, e.App, EventCatalogCreated, e)
+	case tracker.EventMarked:
+		return p.d.Emit(ctx, e.App, EventCatalogMarked, e)
+	}
+	return nil
+}
+
+// Listener of new steam discounts, to wrap a steam store with.
+func (d *Dispatcher) DiscountListener() steam.DiscountListener {
+	return func(ctx context.Context, cc string, games []steam.SteamGame) {
+		data := struct {
+			CountryCode string            `json:"cc"`
+			Games       []steam.SteamGame `json:"games"`
+		}{cc, games}
+		if err := d.Emit(ctx, "", EventSteamDiscount, data); err != nil {
+			logging.Errorf(ctx, "failed to emit steam discount webhooks: %v", err)
+		}
+	}
+}
+
+// Emit the streaks broken yesterday, run once a day.
+func (d *Dispatcher) EmitBrokenStreaks(ctx context.Context, store tracker.Store, now time.Time) error {
+	streaks, err := store.GetBrokenStreaks(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, streak := range streaks {
+		if err := d.Emit(ctx, streak.App, EventStreakBroken, streak); err != nil {
+			return err
+		}
+	}
+	return nil
+}
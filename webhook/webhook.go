@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const webhookTableName = "webhook"
+const deliveryTableName = "webhook_delivery"
+const appTableName = "app"
+
+// Events webhooks can subscribe to.
+const (
+	EventCatalogCreated = "catalog.created"
+	EventCatalogMarked  = "catalog.marked"
+	EventStreakBroken   = "streak.broken"
+	EventSteamDiscount  = "steam.discount"
+)
+
+var Events = []string{
+	EventCatalogCreated, EventCatalogMarked, EventStreakBroken, EventSteamDiscount,
+}
+
+// Prefix of signing secrets.
+const secretPrefix = "whsec_"
+
+var (
+	queryWebhooksByApp   string
+	queryWebhooksByEvent string
+	insertWebhook        string
+	lockAppWebhooks      string
+	queryWebhookCount    string
+	disableWebhook       string
+	queryDeliveries      string
+
+	// Returned when a webhook doesn't exist or belongs to another app.
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// Returned when registering a webhook would exceed the app's quota.
+	ErrTooManyWebhooks = errors.New("too many webhooks")
+)
+
+// Prepare queries.
+func init() {
+	fields := "id, app, url, events, created_at"
+	queryWebhooksByApp = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE app = $1 AND disabled IS FALSE ORDER BY id",
+		fields, webhookTableName)
+
+	// Events are stored comma separated, e.g. "catalog.created,streak.broken".
+	// An empty app matches webhooks of every app.
+	// Disabled apps get no events.
+	queryWebhooksByEvent = fmt.Sprintf(
+		"SELECT id FROM %s WHERE ($1 = '' OR app = $1) AND disabled IS FALSE "+
+			"AND $2 = ANY (string_to_array(events, ',')) "+
+			"AND app IN (SELECT name FROM %s WHERE disabled_at IS NULL)",
+		webhookTableName, appTableName)
+
+	insertWebhook = fmt.Sprintf(
+		"INSERT INTO %s (app, url, secret, events) VALUES ($1, $2, $3, $4) "+
+			"RETURNING id, created_at",
+		webhookTableName)
+
+	// Serializes registrations of one app until the transaction ends. Rows
+	// referencing the app only need a key share lock, so they aren't held up.
+	lockAppWebhooks = fmt.Sprintf(
+		"SELECT name FROM %s WHERE name = $1 FOR NO KEY UPDATE", appTableName)
+
+	queryWebhookCount = fmt.Sprintf(
+		"SELECT count(*) FROM %s WHERE app = $1 AND disabled IS FALSE", webhookTableName)
+
+	disableWebhook = fmt.Sprintf(
+		"UPDATE %s SET disabled = TRUE WHERE id = $1 AND app = $2 AND disabled IS FALSE",
+		webhookTableName)
+
+	queryDeliveries = fmt.Sprintf(
+		"SELECT d.id, d.webhook_id, d.event, d.status, d.attempts, d.response_status, "+
+			"d.last_error, d.next_attempt_at, d.created_at, d.delivered_at "+
+			"FROM %s d JOIN %s w ON w.id = d.webhook_id "+
+			"WHERE d.webhook_id = $1 AND w.app = $2 ORDER BY d.id DESC LIMIT $3",
+		deliveryTableName, webhookTableName)
+}
+
+// Corresponds to rows in `webhook` table. The secret is only returned when
+// the webhook is registered.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	App       string    `json:"app"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Check the webhook before registering it.
+func (wh *Webhook) Validate() error {
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("'url' must be an absolute http(s) URL")
+	}
+	// Obvious cases only, deliveries check the address they connect to.
+	host := u.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && isInternal(ip)) || host == "localhost" ||
+		strings.HasSuffix(host, ".localhost") {
+		return errors.New("'url' must not point to an internal address")
+	}
+	if len(wh.Events) == 0 {
+		return errors.New("'events' must not be empty")
+	}
+	for _, event := range wh.Events {
+		if !isValidEvent(event) {
+			return fmt.Errorf(
+				"unknown event %q, valid events are: %s", event, strings.Join(Events, ", "))
+		}
+	}
+	return nil
+}
+
+func isValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Register a webhook of an app, generating its signing secret. The app keeps
+// at most maxWebhooks active ones unless it's 0.
+func Register(ctx context.Context, db *sql.DB, wh Webhook, maxWebhooks int) (*Webhook, error) {
+	if err := wh.Validate(); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	wh.Secret = secretPrefix + hex.EncodeToString(secret)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var app string
+	if err = tx.QueryRowContext(ctx, lockAppWebhooks, wh.App).Scan(&app); err != nil {
+		return nil, err
+	}
+	if maxWebhooks > 0 {
+		var n int
+		if err = tx.QueryRowContext(ctx, queryWebhookCount, wh.App).Scan(&n); err != nil {
+			return nil, err
+		}
+		if n >= maxWebhooks {
+			return nil, ErrTooManyWebhooks
+		}
+	}
+
+	err = tx.QueryRowContext(
+		ctx, insertWebhook, wh.App, wh.URL, wh.Secret, strings.Join(wh.Events, ","),
+	).Scan(&wh.ID, &wh.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &wh, tx.Commit()
+}
+
+// Stop delivering to a webhook, its delivery log is kept.
+func Unregister(ctx context.Context, db *sql.DB, id int64, app string) error {
+	res, err := db.ExecContext(ctx, disableWebhook, id, app)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Get active webhooks of an app, without secrets.
+func GetWebhooks(ctx context.Context, db *sql.DB, app string) ([]Webhook, error) {
+	rows, err := db.QueryContext(ctx, queryWebhooksByApp, app)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]Webhook, 0)
+	for rows.Next() {
+		var wh Webhook
+		var events string
+		if err := rows.Scan(&wh.ID, &wh.App, &wh.URL, &events, &wh.CreatedAt); err != nil {
+			return nil, err
+		}
+		wh.Events = strings.Split(events, ",")
+		res = append(res, wh)
+	}
+	return res, rows.Err()
+}
+
+// One attempted or pending delivery of an event to a webhook.
+type Delivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhookId"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"responseStatus,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Get the latest deliveries of a webhook of the app, most recent first.
+func GetDeliveries(ctx context.Context, db *sql.DB, webhookID int64, app string, limit int) ([]Delivery, error) {
+	rows, err := db.QueryContext(ctx, queryDeliveries, webhookID, app, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]Delivery, 0)
+	for rows.Next() {
+		var d Delivery
+		var nextAttemptAt time.Time
+		err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.Event, &d.Status, &d.Attempts, &d.ResponseStatus,
+			&d.LastError, &nextAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		if d.Status == StatusPending {
+			d.NextAttemptAt = &nextAttemptAt
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}